@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	aurora "github.com/logrusorgru/aurora/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+// Argument of cache export and import commands that stands for stdout or stdin
+const cacheArchiveStdio = "-"
+
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the vendir cache",
+		Long: `List, inspect, verify, export, and import entries of the vendir cache.
+
+Applications referencing a cache entry are taken from their links maps,
+which are written during sync. Export and import move the cache as a gzipped tarball,
+e.g. to prepare the cache for air-gapped CI runners.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(cmd)
+		},
+	}
+
+	cacheCmd.PersistentFlags().StringP("output", "o", inspectOutputText, `output format: "text" or "json"`)
+	cacheCmd.AddCommand(newCacheListCmd())
+	cacheCmd.AddCommand(newCacheInspectCmd())
+	cacheCmd.AddCommand(newCacheVerifyCmd())
+	cacheCmd.AddCommand(newCacheExportCmd())
+	cacheCmd.AddCommand(newCacheImportCmd())
+	return cacheCmd
+}
+
+// initCacheGlobe initializes all environments and applications to resolve references to cache entries.
+func initCacheGlobe() (*myks.Globe, error) {
+	g := getGlobe()
+	if err := g.ValidateRootDir(); err != nil {
+		return nil, fmt.Errorf("root directory is not suitable for myks: %w", err)
+	}
+	if err := g.Init(asyncLevel, nil); err != nil {
+		return nil, fmt.Errorf("unable to initialize myks' globe: %w", err)
+	}
+	return g, nil
+}
+
+func newCacheListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cache entries with their sources and referencing applications",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g, err := initCacheGlobe()
+			if err != nil {
+				return err
+			}
+			entries, err := g.ListCacheEntries()
+			if err != nil {
+				return fmt.Errorf("listing cache entries failed: %w", err)
+			}
+			return printOutput(cmd, entries, func() {
+				printCacheEntries(entries)
+			})
+		},
+	}
+}
+
+func newCacheInspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect NAME",
+		Short: "Show details of a cache entry",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g, err := initCacheGlobe()
+			if err != nil {
+				return err
+			}
+			entry, err := g.InspectCacheEntry(args[0])
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd, entry, func() {
+				printCacheEntry(&entry)
+			})
+		},
+	}
+}
+
+func newCacheVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify [NAME...]",
+		Short: "Verify cache entries against their recorded digests",
+		Long: `Verify cache entries against their recorded digests.
+
+The digest of the synced data of every entry is recomputed and compared to the digest
+recorded after the sync. The command fails if any entry does not match.
+Digests are recorded for entries of the shared cache, and for all entries on export.
+Other entries have no digest and are reported as "no-digest".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g := getGlobe()
+			if err := g.ValidateRootDir(); err != nil {
+				return fmt.Errorf("root directory is not suitable for myks: %w", err)
+			}
+			results, err := g.VerifyCacheEntries(args)
+			if err != nil {
+				return fmt.Errorf("verifying cache entries failed: %w", err)
+			}
+			if err = printOutput(cmd, results, func() {
+				printCacheVerifyResults(results)
+			}); err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Status == myks.CacheStatusMismatch || r.Status == myks.CacheStatusError {
+					return errors.New("some cache entries failed verification")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newCacheExportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export FILE [NAME...]",
+		Short: "Export cache entries to a gzipped tarball",
+		Long: `Export cache entries to a gzipped tarball.
+
+All entries are exported if no names are given. Use "-" as FILE to write to stdout.`,
+		Example: `  # Export the whole cache
+  myks cache export vendir-cache.tar.gz`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			g := getGlobe()
+			if err = g.ValidateRootDir(); err != nil {
+				return fmt.Errorf("root directory is not suitable for myks: %w", err)
+			}
+			var w io.Writer = os.Stdout
+			if args[0] != cacheArchiveStdio {
+				f, err := os.Create(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if closeErr := f.Close(); err == nil {
+						err = closeErr
+					}
+				}()
+				w = f
+			}
+			return g.ExportCache(w, args[1:])
+		},
+	}
+}
+
+func newCacheImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import cache entries from a gzipped tarball",
+		Long: `Import cache entries from a gzipped tarball created by "myks cache export".
+
+Entries are verified against their recorded digests before being added to the cache.
+Corrupted entries are skipped, existing entries are kept. Use "-" as FILE to read from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g := getGlobe()
+			if err := g.ValidateRootDir(); err != nil {
+				return fmt.Errorf("root directory is not suitable for myks: %w", err)
+			}
+			var r io.Reader = os.Stdin
+			if args[0] != cacheArchiveStdio {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() { _ = f.Close() }()
+				r = f
+			}
+			results, err := g.ImportCache(r)
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd, results, func() {
+				printCacheImportResults(results)
+			})
+		},
+	}
+}
+
+func printCacheEntries(entries []myks.CacheEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tSOURCE\tVERSION\tSIZE\tLAST USED\tAPPS")
+	for i := range entries {
+		e := &entries[i]
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Name, e.Type, orDash(e.Source), orDash(e.Version), myks.FormatByteSize(e.Size),
+			e.LastUsed.Format("2006-01-02 15:04"), orDash(strings.Join(cacheReferenceApps(e.References), ",")))
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print cache entries: %v\n", err)
+	}
+}
+
+func printCacheEntry(e *myks.CacheEntry) {
+	fmt.Printf("%s %s\n", aurora.Bold("Name:"), e.Name)
+	fmt.Printf("%s %s\n", aurora.Bold("Directory:"), e.Dir)
+	fmt.Printf("%s %s\n", aurora.Bold("Type:"), e.Type)
+	fmt.Printf("%s %s\n", aurora.Bold("Source:"), orDash(e.Source))
+	fmt.Printf("%s %s\n", aurora.Bold("Version:"), orDash(e.Version))
+	fmt.Printf("%s %s\n", aurora.Bold("Size:"), myks.FormatByteSize(e.Size))
+	fmt.Printf("%s %s\n", aurora.Bold("Last used:"), e.LastUsed.Format("2006-01-02 15:04:05"))
+	fmt.Printf("%s %s\n", aurora.Bold("Digest:"), orDash(e.Digest))
+	fmt.Println(aurora.Bold("References:"))
+	if len(e.References) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, ref := range e.References {
+		fmt.Printf("  %s/%s: %s\n", ref.Env, ref.App, ref.Path)
+	}
+	if e.VendirConfig != "" {
+		fmt.Println(aurora.Bold("Vendir config:"))
+		fmt.Print(indentLines(e.VendirConfig))
+	}
+	if e.VendirLock != "" {
+		fmt.Println(aurora.Bold("Vendir lock:"))
+		fmt.Print(indentLines(e.VendirLock))
+	}
+}
+
+func printCacheVerifyResults(results []myks.CacheVerifyResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATUS\tDETAILS")
+	for _, r := range results {
+		status, details := r.Status, r.Error
+		switch r.Status {
+		case myks.CacheStatusOK:
+			status = aurora.Green(status).String()
+		case myks.CacheStatusNoDigest:
+			status = aurora.Faint(status).String()
+		case myks.CacheStatusMismatch:
+			status = aurora.Red(status).String()
+			details = fmt.Sprintf("expected %s, got %s", r.Expected, r.Actual)
+		default:
+			status = aurora.Red(status).String()
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, status, details)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print verification results: %v\n", err)
+	}
+}
+
+func printCacheImportResults(results []myks.CacheImportResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tIMPORTED\tDETAILS")
+	for _, r := range results {
+		imported := aurora.Green("yes").String()
+		if !r.Imported {
+			imported = aurora.Yellow("no").String()
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, imported, r.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print import results: %v\n", err)
+	}
+}
+
+// cacheReferenceApps returns unique "env/app" names of the references, keeping their order.
+func cacheReferenceApps(refs []myks.CacheReference) []string {
+	var apps []string
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		app := ref.Env + "/" + ref.App
+		if !seen[app] {
+			seen[app] = true
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func indentLines(s string) string {
+	var b strings.Builder
+	for line := range strings.Lines(s) {
+		b.WriteString("  " + line)
+	}
+	if !strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
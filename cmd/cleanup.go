@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
 )
 
 const cleanupCmdLongHelp = `Cleanup obsolete manifests and cache entries.
@@ -20,6 +24,13 @@ Examples:
 
     # List cache entries that would be cleaned up
     myks cleanup --cache --dry-run
+
+    # Evict cache entries not used for 30 days, and the least recently used ones above 10GiB
+    myks cleanup --cache --max-age 30d --max-size 10GiB
+
+With --max-age or --max-size, cache entries are evicted by their last usage instead of
+by references from the current tree. This keeps entries used by other branches, while
+limiting the growth of persistent caches, e.g. on CI runners.
 `
 
 func newCleanupCmd() *cobra.Command {
@@ -36,6 +47,10 @@ func newCleanupCmd() *cobra.Command {
 
 			modeManifests, modeManifestsSet := readFlagBool(cmd, "manifests")
 			modeCache, modeCacheSet := readFlagBool(cmd, "cache")
+			maxAge, maxSize, err := readCacheLimits(cmd)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Invalid cache limits")
+			}
 
 			if !modeManifestsSet && !modeCacheSet {
 				modeManifests = true
@@ -62,7 +77,11 @@ func newCleanupCmd() *cobra.Command {
 				}
 			}
 
-			if modeCache {
+			if modeCache && (maxAge > 0 || maxSize > 0) {
+				if err := g.CleanupCacheByUsage(maxAge, maxSize, dryRun); err != nil {
+					log.Fatal().Err(err).Msg("Unable to cleanup cache entries")
+				}
+			} else if modeCache {
 				if err := g.CleanupObsoleteCacheEntries(dryRun); err != nil {
 					log.Fatal().Err(err).Msg("Unable to cleanup cache entries")
 				}
@@ -73,6 +92,27 @@ func newCleanupCmd() *cobra.Command {
 	cmd.Flags().Bool("dry-run", false, "print what would be cleaned up without actually cleaning up")
 	cmd.Flags().Bool("manifests", false, "cleanup rendered manifests")
 	cmd.Flags().Bool("cache", false, "cleanup cache entries")
+	cmd.Flags().String("max-age", "", "evict cache entries not used for this long, e.g. 30d, 2w, 12h")
+	cmd.Flags().String("max-size", "", "evict least recently used cache entries above this size, e.g. 10GiB, 500MB")
 
 	return cmd
 }
+
+// readCacheLimits parses the --max-age and --max-size flags, zero values mean no limit.
+func readCacheLimits(cmd *cobra.Command) (time.Duration, int64, error) {
+	var maxAge time.Duration
+	var maxSize int64
+	if value, _ := cmd.Flags().GetString("max-age"); value != "" {
+		var err error
+		if maxAge, err = myks.ParseAge(value); err != nil {
+			return 0, 0, err
+		}
+	}
+	if value, _ := cmd.Flags().GetString("max-size"); value != "" {
+		var err error
+		if maxSize, err = myks.ParseByteSize(value); err != nil {
+			return 0, 0, err
+		}
+	}
+	return maxAge, maxSize, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+const lockCmdLongHelp = `Resolve vendir sources and write lock files.
+
+For every application, the sources defined in the vendir config are resolved to exact versions:
+git refs to commit SHAs, helm chart versions to the synced chart digest, image tags to image digests.
+The result is written to a lock file in the application directory of the environment,
+e.g. envs/prod/_apps/app1/myks.lock.yaml, which is meant to be committed.
+
+When a lock file exists, "myks render" syncs the pinned versions. With "myks render --frozen",
+rendering fails if a lock file is missing or the vendir config was changed without re-locking.`
+
+func newLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Resolve vendir sources and write lock files",
+		Long:  lockCmdLongHelp,
+		Args:  cobra.RangeArgs(0, 2),
+		Annotations: map[string]string{
+			AnnotationSmartMode: AnnotationTrue,
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			okOrFatal(LockCmd(getGlobe()), "Locking failed")
+		},
+		ValidArgsFunction: shellCompletion,
+	}
+	cmd.SetUsageTemplate(envAppCommandUsageTemplate)
+	return cmd
+}
+
+// LockCmd resolves vendir sources of the selected applications and writes their lock files.
+func LockCmd(g *myks.Globe) error {
+	if err := g.ValidateRootDir(); err != nil {
+		return fmt.Errorf("root directory is not suitable for myks: %w", err)
+	}
+	if err := g.Init(asyncLevel, envAppMap); err != nil {
+		return fmt.Errorf("unable to initialize myks' globe: %w", err)
+	}
+	g.UpdateLock = true
+	if err := g.Run(asyncLevel, true, false); err != nil {
+		return fmt.Errorf("run failed: %w", err)
+	}
+	return nil
+}
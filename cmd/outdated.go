@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	aurora "github.com/logrusorgru/aurora/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+const outdatedCmdLongHelp = `Report newer versions of vendored sources.
+
+For every application, the vendir config is rendered and the sources are checked for newer versions:
+helm charts against the repository index (or OCI tags for oci:// repositories),
+images and imgpkg bundles against OCI tags, and git sources against repository tags.
+
+Only semantic versions are considered. Pre-releases are reported only if the current version is a pre-release.
+The UPDATE column classifies the newest version as "major", "minor", "patch", "release", "prerelease", or "up-to-date".
+A "release" update is the final release of the current pre-release, e.g. from 1.2.0-rc.1 to 1.2.0.
+Sources referencing a vendir secret are checked with the credentials used by sync.
+Sources with non-semantic versions, e.g. git branches, are reported as "unknown".`
+
+func newOutdatedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "outdated [env-selector [app-selector]]",
+		Short:             "Report newer versions of vendored sources",
+		Long:              outdatedCmdLongHelp,
+		Args:              cobra.MaximumNArgs(2),
+		ValidArgsFunction: shellCompletion,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g := getGlobe()
+			if err := g.ValidateRootDir(); err != nil {
+				return fmt.Errorf("root directory is not suitable for myks: %w", err)
+			}
+			if err := g.Init(asyncLevel, parseInspectEnvAppMap(args)); err != nil {
+				return fmt.Errorf("unable to initialize myks' globe: %w", err)
+			}
+
+			resolver := myks.NewVersionResolver()
+			if err := resolver.LoadCredentials(g); err != nil {
+				return fmt.Errorf("unable to load vendir secrets: %w", err)
+			}
+			sources, err := g.Outdated(asyncLevel, resolver)
+			if err != nil {
+				return fmt.Errorf("checking sources failed: %w", err)
+			}
+
+			return printOutput(cmd, sources, func() {
+				printOutdatedSources(sources)
+			})
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", inspectOutputText, `output format: "text" or "json"`)
+	cmd.SetUsageTemplate(inspectAppsUsageTemplate)
+	return cmd
+}
+
+func printOutdatedSources(sources []myks.OutdatedSource) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ENV\tAPP\tPATH\tTYPE\tNAME\tCURRENT\tLATEST\tUPDATE")
+	for _, s := range sources {
+		latest := s.Latest
+		if latest == "" {
+			latest = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Env, s.App, s.Path, s.Type, s.Name, s.Current, latest, colorizeUpdate(s.Update))
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print sources: %v\n", err)
+	}
+}
+
+func colorizeUpdate(update string) string {
+	switch update {
+	case myks.UpdateMajor:
+		return aurora.Red(update).String()
+	case myks.UpdateMinor:
+		return aurora.Yellow(update).String()
+	case myks.UpdatePatch, myks.UpdateRelease:
+		return aurora.Green(update).String()
+	default:
+		return aurora.Faint(update).String()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	gv "github.com/hashicorp/go-version"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,6 +15,9 @@ import (
 
 const pluginPrefix = "myks-"
 
+// plugins are the plugins added as commands, available to hooks
+var plugins []myks.Plugin
+
 // findPlugins searches for plugins in the PATH and in configured plugin-sources.
 // Executables in PATH must have the prefix defined in pluginPrefix.
 // Executables in plugin-sources can have any name.
@@ -30,7 +34,7 @@ func findPlugins() []myks.Plugin {
 	return append(pluginsPath, pluginsLocal...)
 }
 
-func addPlugins(cmd *cobra.Command) {
+func addPlugins(cmd *cobra.Command, version string) {
 	plugins := findPlugins()
 
 	uniquePlugins := make(map[string]myks.Plugin)
@@ -51,22 +55,39 @@ func addPlugins(cmd *cobra.Command) {
 	}
 
 	for _, plugin := range uniquePlugins {
-		cmd.AddCommand(newPluginCmd(plugin))
+		cmd.AddCommand(newPluginCmd(cmd, plugin, version))
+		plugins = append(plugins, plugin)
 	}
 }
 
-func newPluginCmd(plugin myks.Plugin) *cobra.Command {
+func newPluginCmd(root *cobra.Command, plugin myks.Plugin, version string) *cobra.Command {
+	manifest := plugin.Manifest()
+	short := "Execute " + plugin.Name() + " plugin"
+	long := "Execute the " + plugin.Name() + " plugin for specified environments and applications."
+	if manifest.Description != "" {
+		short = manifest.Description
+		long = manifest.Description
+	}
+	if manifest.Long != "" {
+		long = manifest.Long
+	}
+
 	cmd := &cobra.Command{
 		Use:     plugin.Name() + " [environments [applications]] [flags] [-- <plugin-args>...]",
-		Short:   "Execute " + plugin.Name() + " plugin",
-		Long:    "Execute the " + plugin.Name() + " plugin for specified environments and applications.",
+		Short:   short,
+		Long:    long,
 		GroupID: "Plugins",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkPluginMinVersion(manifest.MinVersion, version); err != nil {
+				return fmt.Errorf("plugin %s: %w", plugin.Name(), err)
+			}
+
 			splitAt := cmd.ArgsLenAtDash()
 			if splitAt == -1 {
 				splitAt = len(args)
 			}
 			myksArgs, pluginArgs := args[:splitAt], args[splitAt:]
+			pluginArgs = append(pluginFlagArgs(cmd, manifest.Flags), pluginArgs...)
 
 			if len(myksArgs) > 2 {
 				return fmt.Errorf("expected at most 2 positional arguments (environments and applications), got %d", len(myksArgs))
@@ -95,15 +116,105 @@ func newPluginCmd(plugin myks.Plugin) *cobra.Command {
 
 			return nil
 		},
-		ValidArgsFunction: shellCompletion,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// Arguments after -- are passed to the plugin
+			if cmd.ArgsLenAtDash() >= 0 {
+				return manifest.Args, cobra.ShellCompDirectiveNoFileComp
+			}
+			return shellCompletion(cmd, args, toComplete)
+		},
 	}
 
-	cmd.SetUsageTemplate(pluginUsageTemplate(plugin.Name()))
+	addPluginFlags(root, cmd, plugin.Name(), manifest.Flags)
+	cmd.SetUsageTemplate(pluginUsageTemplate(plugin.Name(), manifest.Scope))
 
 	return cmd
 }
 
-func pluginUsageTemplate(pluginName string) string {
+// addPluginFlags adds the flags declared in a plugin manifest to the plugin command.
+// Flags conflicting with the persistent flags of myks are skipped.
+func addPluginFlags(root, cmd *cobra.Command, pluginName string, flags []myks.PluginFlag) {
+	for _, flag := range flags {
+		if root.PersistentFlags().Lookup(flag.Name) != nil ||
+			(flag.Shorthand != "" && root.PersistentFlags().ShorthandLookup(flag.Shorthand) != nil) {
+			log.Warn().Str("plugin", pluginName).Str("flag", flag.Name).Msg("Plugin flag conflicts with a myks flag, skipping")
+			continue
+		}
+		if flag.Type == myks.PluginFlagTypeBool {
+			cmd.Flags().BoolP(flag.Name, flag.Shorthand, flag.Default == "true", flag.Description)
+		} else {
+			cmd.Flags().StringP(flag.Name, flag.Shorthand, flag.Default, flag.Description)
+		}
+		if len(flag.Values) > 0 {
+			values := flag.Values
+			_ = cmd.RegisterFlagCompletionFunc(flag.Name, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+				return values, cobra.ShellCompDirectiveNoFileComp
+			})
+		}
+	}
+}
+
+// pluginFlagArgs converts the declared plugin flags set on the command line to plugin arguments.
+func pluginFlagArgs(cmd *cobra.Command, flags []myks.PluginFlag) []string {
+	var args []string
+	for _, flag := range flags {
+		f := cmd.Flags().Lookup(flag.Name)
+		if f == nil || !f.Changed {
+			continue
+		}
+		args = append(args, "--"+flag.Name+"="+f.Value.String())
+	}
+	return args
+}
+
+// checkPluginMinVersion fails if the current version of myks is lower than the version required by a plugin.
+// Development builds with an invalid version are not checked.
+func checkPluginMinVersion(minVersion, current string) error {
+	if minVersion == "" {
+		return nil
+	}
+	required, err := gv.NewVersion(minVersion)
+	if err != nil {
+		return fmt.Errorf("invalid min-version %q: %w", minVersion, err)
+	}
+	v, err := gv.NewVersion(current)
+	if err != nil {
+		log.Debug().Err(err).Str("current-version", current).Msg("Invalid current version, skipping plugin min-version check")
+		return nil
+	}
+	if v.LessThan(required) {
+		return fmt.Errorf("myks %s or newer is required, current version is %s", minVersion, current)
+	}
+	return nil
+}
+
+// pluginEnvVarsUsage lists the environment variables passed to plugins of each scope.
+var pluginEnvVarsUsage = map[string]string{
+	myks.PluginScopeApp: `  The plugin runs once per application and receives the following environment variables:
+    MYKS_ENV              - Environment ID
+    MYKS_APP              - Application name
+    MYKS_APP_PROTOTYPE    - Application prototype name
+    MYKS_ENV_DIR          - Environment directory path
+    MYKS_RENDERED_APP_DIR - Rendered application directory path
+    MYKS_DATA_VALUES      - YAML data values for the application, unset if too large
+    MYKS_CONTEXT_DIR      - Directory with the data values as YAML and JSON, and the rendered and source files`,
+	myks.PluginScopeEnv: `  The plugin runs once per environment and receives the following environment variables:
+    MYKS_ENV              - Environment ID
+    MYKS_ENV_DIR          - Environment directory path
+    MYKS_RENDERED_ENV_DIR - Rendered environment directory path
+    MYKS_APPS             - Comma-separated list of selected applications
+    MYKS_DATA_VALUES      - YAML data values for the environment, unset if too large
+    MYKS_CONTEXT_DIR      - Directory with the data values as YAML and JSON`,
+	myks.PluginScopeGlobal: `  The plugin runs once and receives the following environment variables:
+    MYKS_ROOT_DIR         - Project root directory path
+    MYKS_ENVS             - Comma-separated list of selected environment IDs`,
+}
+
+func pluginUsageTemplate(pluginName, scope string) string {
+	envVarsUsage, ok := pluginEnvVarsUsage[scope]
+	if !ok {
+		envVarsUsage = pluginEnvVarsUsage[myks.PluginScopeApp]
+	}
 	return `Usage:
   {{.CommandPath}} [environments [applications]] [flags] [-- <plugin-args>...]
 
@@ -124,13 +235,7 @@ Arguments:
 {{.Flags.FlagUsages | trimTrailingWhitespaces}}{{end}}
 
 Environment Variables:
-  The plugin receives the following environment variables:
-    MYKS_ENV              - Environment ID
-    MYKS_APP              - Application name
-    MYKS_APP_PROTOTYPE    - Application prototype name
-    MYKS_ENV_DIR          - Environment directory path
-    MYKS_RENDERED_APP_DIR - Rendered application directory path
-    MYKS_DATA_VALUES      - YAML data values for the application
+` + envVarsUsage + `
 
 Examples:
   # Run ` + pluginName + ` for all environments and applications
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+type fakePlugin struct {
+	manifest myks.PluginManifest
+}
+
+func (p fakePlugin) Exec(*myks.Application, []string, bool) error    { return nil }
+func (p fakePlugin) ExecEnv(*myks.Environment, []string, bool) error { return nil }
+func (p fakePlugin) ExecGlobal(*myks.Globe, []string, bool) error    { return nil }
+func (p fakePlugin) Name() string                                    { return "fake" }
+func (p fakePlugin) Manifest() myks.PluginManifest                   { return p.manifest }
+
+func Test_newPluginCmd_manifest(t *testing.T) {
+	root := &cobra.Command{Use: "myks"}
+	root.PersistentFlags().IntP("async", "a", 0, "")
+	root.AddGroup(&cobra.Group{ID: "Plugins", Title: "Plugin Subcommands:"})
+
+	cmd := newPluginCmd(root, fakePlugin{manifest: myks.PluginManifest{
+		Description: "Fake plugin",
+		Flags: []myks.PluginFlag{
+			{Name: "wait", Type: myks.PluginFlagTypeBool},
+			{Name: "output", Shorthand: "o", Default: "text"},
+			{Name: "async"},
+			{Name: "all", Shorthand: "a"},
+		},
+	}}, "v5.0.0")
+
+	assert.Equal(t, "Fake plugin", cmd.Short)
+	assert.NotNil(t, cmd.Flags().Lookup("wait"))
+	assert.NotNil(t, cmd.Flags().ShorthandLookup("o"))
+	assert.Nil(t, cmd.Flags().Lookup("async"), "conflicting flags are skipped")
+	assert.Nil(t, cmd.Flags().Lookup("all"), "conflicting shorthands are skipped")
+
+	flags := []myks.PluginFlag{{Name: "wait"}, {Name: "output"}}
+	require.NoError(t, cmd.ParseFlags([]string{"--wait"}))
+	assert.Equal(t, []string{"--wait=true"}, pluginFlagArgs(cmd, flags), "flags with default values are not forwarded")
+	require.NoError(t, cmd.ParseFlags([]string{"-o", "json"}))
+	assert.Equal(t, []string{"--wait=true", "--output=json"}, pluginFlagArgs(cmd, flags))
+}
+
+func Test_checkPluginMinVersion(t *testing.T) {
+	require.NoError(t, checkPluginMinVersion("", "v1.0.0"))
+	require.NoError(t, checkPluginMinVersion("v5.0.0", "v5.1.0"))
+	require.NoError(t, checkPluginMinVersion("v5.0.0", "dev"), "development builds are not checked")
+	require.Error(t, checkPluginMinVersion("v5.0.0", "v4.9.0"))
+	require.Error(t, checkPluginMinVersion("latest", "v5.0.0"))
+}
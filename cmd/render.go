@@ -3,40 +3,14 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/mykso/myks/internal/myks"
 )
 
-func newRenderCmd() *cobra.Command {
-	renderCmd := &cobra.Command{
-		Use:   "render",
-		Short: "Render application manifests",
-		Long: `Download external sources and render manifests for specified environments and applications.
-
-Authentication against protected repositories is achieved with environment variables prefixed with "VENDIR_SECRET_".
-For example, if you reference a secret named "mycreds" in your vendir.yaml, you need to export the variables "VENDIR_SECRET_MYCREDS_USERNAME" and
-"VENDIR_SECRET_MYCREDS_PASSWORD" in your environment.`,
-		Args: cobra.RangeArgs(0, 2),
-		Annotations: map[string]string{
-			AnnotationSmartMode: AnnotationTrue,
-		},
-		Run: func(cmd *cobra.Command, args []string) {
-			sync, syncSet := readFlagBool(cmd, "sync")
-			render, renderSet := readFlagBool(cmd, "render")
-
-			if !syncSet && !renderSet {
-				sync = true
-				render = true
-			}
-
-			okOrFatal(RenderCmd(getGlobe(), sync, render), "Rendering failed")
-		},
-		ValidArgsFunction: shellCompletion,
-	}
-
-	// Use this template for commands that accept environment and application arguments
-	envAppCommandUsageTemplate := `Usage:
+// envAppCommandUsageTemplate is the usage template for commands that accept environment and application arguments.
+const envAppCommandUsageTemplate = `Usage:
   {{.CommandPath}} [environments [applications]] [flags]
 
 Arguments:
@@ -68,11 +42,45 @@ Examples:
   {{.CommandPath}} prod,stage app1,app2
 `
 
+func newRenderCmd() *cobra.Command {
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render application manifests",
+		Long: `Download external sources and render manifests for specified environments and applications.
+
+Authentication against protected repositories is achieved with environment variables prefixed with "VENDIR_SECRET_".
+For example, if you reference a secret named "mycreds" in your vendir.yaml, you need to export the variables "VENDIR_SECRET_MYCREDS_USERNAME" and
+"VENDIR_SECRET_MYCREDS_PASSWORD" in your environment. Tokens and SSH keys are read from the "_TOKEN",
+"_SSH_PRIVATE_KEY", and "_SSH_KNOWN_HOSTS" variables. Other credential sources are configured with "vendir-credentials".`,
+		Args: cobra.RangeArgs(0, 2),
+		Annotations: map[string]string{
+			AnnotationSmartMode:        AnnotationTrue,
+			AnnotationSmartModeCleanup: AnnotationTrue,
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			sync, syncSet := readFlagBool(cmd, "sync")
+			render, renderSet := readFlagBool(cmd, "render")
+
+			if !syncSet && !renderSet {
+				sync = true
+				render = true
+			}
+
+			g := getGlobe()
+			g.FrozenLock, _ = readFlagBool(cmd, "frozen")
+			g.Offline, _ = readFlagBool(cmd, "offline")
+			okOrFatal(RenderCmd(g, sync, render), "Rendering failed")
+		},
+		ValidArgsFunction: shellCompletion,
+	}
+
 	renderCmd.SetUsageTemplate(envAppCommandUsageTemplate)
 
 	renderCmd.Flags().BoolP("sync", "s", false, "only sync external sources")
 	renderCmd.Flags().BoolP("render", "r", false, "only render manifests")
 	renderCmd.MarkFlagsMutuallyExclusive("sync", "render")
+	renderCmd.Flags().Bool("frozen", false, "fail if a lock file is missing or does not match the vendir config")
+	renderCmd.Flags().Bool("offline", false, "sync from the vendir cache only and fail if sources are missing from it")
 
 	return renderCmd
 }
@@ -90,11 +98,21 @@ func RenderCmd(g *myks.Globe, sync, render bool) error {
 		return fmt.Errorf("run failed: %w", err)
 	}
 
-	// Cleaning up only if all environments and applications were processed
+	// Cleaning up everything only if all environments and applications were processed
 	if envAppMap == nil {
 		if err := g.CleanupRenderedManifests(false); err != nil {
 			return fmt.Errorf("unable to cleanup rendered manifests: %w", err)
 		}
+	} else {
+		g.CleanupSmartModeDeletions(false)
+	}
+
+	// The manifest allows Smart Mode to detect changes without git; explicitly selected applications
+	// don't cover all changes, so the manifest is kept
+	if render && !g.WithGit && (envAppMap == nil || smartModeSelection) {
+		if err := g.SaveChangeManifest(); err != nil {
+			log.Warn().Err(err).Msg("Unable to save the Smart Mode change manifest")
+		}
 	}
 
 	return nil
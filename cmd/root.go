@@ -30,6 +30,8 @@ const (
 var (
 	cfgFile   string
 	envAppMap myks.EnvAppMap
+	// envAppMap was selected by Smart Mode
+	smartModeSelection bool
 	// TODO: change to uint
 	asyncLevel int
 
@@ -45,7 +47,13 @@ func NewMyksCmd(version, commit, date string) *cobra.Command {
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	cmd := newRootCmd(version, commit, date)
 	cmd.AddCommand(newRenderCmd())
+	cmd.AddCommand(newWatchCmd())
 	cmd.AddCommand(newCleanupCmd())
+	cmd.AddCommand(newLockCmd())
+	cmd.AddCommand(newOutdatedCmd())
+	cmd.AddCommand(newUpdateCmd())
+	cmd.AddCommand(newCacheCmd())
+	cmd.AddCommand(newCredentialsCmd())
 	cmd.AddCommand(newInitCmd(version))
 	cmd.AddCommand(newPrintConfigCmd())
 	cmd.AddCommand(newInspectCmd())
@@ -53,7 +61,7 @@ func NewMyksCmd(version, commit, date string) *cobra.Command {
 	cmd.AddCommand(embedded.Cmd("ytt", "Ytt is embedded in myks to manage yaml files."))
 	cmd.AddCommand(embedded.Cmd("kbld", "Kbld is embedded in myks to manage container image references."))
 	initConfig()
-	addPlugins(cmd)
+	addPlugins(cmd, version)
 
 	return cmd
 }
@@ -82,6 +90,7 @@ CORE FEATURES
 BASIC COMMANDS
   init     Create a new Myks project in the current directory
   render   Download external sources and render manifests for specified environments and applications
+  watch    Re-render affected applications on file changes
 
 GETTING STARTED
   1. Create a new project: myks init
@@ -114,6 +123,10 @@ LEARN MORE
 	rootCmd.PersistentFlags().String("smart-mode.only-print", "", smartModeOnlyPrintHelp)
 	rootCmd.PersistentFlags().Lookup("smart-mode.only-print").NoOptDefVal = outputFormatText
 
+	smartModeExplainHelp := "show the reasons each environment and application was selected by Smart Mode\n" +
+		"extends the only-print output, or logs the reasons before running the command"
+	rootCmd.PersistentFlags().Bool("smart-mode.explain", false, smartModeExplainHelp)
+
 	bufferPluginOutputHelp := "buffer plugin output instead of streaming (useful for parallel execution)"
 	rootCmd.PersistentFlags().Bool("buffer-plugin-output", false, bufferPluginOutputHelp)
 
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	aurora "github.com/logrusorgru/aurora/v4"
@@ -18,7 +20,9 @@ import (
 // AnnotationSmartMode is the commit annotation checked for enabling Smart Mode.
 const (
 	AnnotationSmartMode = "feat:smart-mode"
-	AnnotationTrue      = "true"
+	// AnnotationSmartModeCleanup marks commands that remove rendered manifests of deleted sources detected by Smart Mode.
+	AnnotationSmartModeCleanup = "feat:smart-mode-cleanup"
+	AnnotationTrue             = "true"
 
 	outputFormatText = "text"
 	outputFormatJSON = "json"
@@ -43,7 +47,7 @@ func normalizeOnlyPrint(raw string) (string, error) {
 	}
 }
 
-func initTargetEnvsAndApps(_ *cobra.Command, args []string) (err error) {
+func initTargetEnvsAndApps(cmd *cobra.Command, args []string) (err error) {
 	// Check positional arguments for Smart Mode:
 	// 1. Comma-separated list of environment search paths/IDs or ALL to search everywhere (default: ALL)
 	// 2. Comma-separated list of application names or none to process all applications (default: none)
@@ -52,11 +56,13 @@ func initTargetEnvsAndApps(_ *cobra.Command, args []string) (err error) {
 	if err != nil {
 		return err
 	}
+	explain := viper.GetBool("smart-mode.explain")
 
 	switch len(args) {
 	case 0:
 		g := getGlobe()
 		envAppMap, err = g.DetectChangedEnvsAndApps(viper.GetString("smart-mode.base-revision"))
+		smartModeSelection = err == nil
 		if err != nil {
 			if onlyPrint != "" {
 				return fmt.Errorf("smart mode detection failed: %w", err)
@@ -69,41 +75,18 @@ func initTargetEnvsAndApps(_ *cobra.Command, args []string) (err error) {
 				os.Exit(0)
 			case outputFormatText:
 				fmt.Println(aurora.Bold("\nSmart Mode detected no changes."))
+				printSmartModeDeletions(g)
 				os.Exit(0)
 			default:
+				if cmd.Annotations[AnnotationSmartModeCleanup] == AnnotationTrue {
+					g.CleanupSmartModeDeletions(false)
+				}
 				log.Warn().Msg("Smart Mode did not find any changes. Exiting.")
 				os.Exit(0)
 			}
 		}
-	case 1:
-		if args[0] != allEnvsToken {
-			g := getGlobe()
-			envAppMap = make(myks.EnvAppMap)
-			for env := range strings.SplitSeq(args[0], ",") {
-				// Resolve environment ID to path if needed
-				resolvedEnv := g.ResolveEnvIdentifier(env)
-				envAppMap[resolvedEnv] = nil
-			}
-		}
-	case 2:
-		var appNames []string
-		if args[1] != allEnvsToken {
-			appNames = strings.Split(args[1], ",")
-		}
-
-		envAppMap = make(myks.EnvAppMap)
-		if args[0] != allEnvsToken {
-			g := getGlobe()
-			for env := range strings.SplitSeq(args[0], ",") {
-				// Resolve environment ID to path if needed
-				resolvedEnv := g.ResolveEnvIdentifier(env)
-				envAppMap[resolvedEnv] = appNames
-			}
-		} else {
-			g := getGlobe()
-			envAppMap[g.EnvironmentBaseDir] = appNames
-		}
-
+	case 1, 2:
+		envAppMap = parseEnvAppArgs(getGlobe(), args)
 	default:
 		err := errors.New("too many positional arguments")
 		log.Error().Err(err).Msg("Unable to parse positional arguments")
@@ -122,8 +105,15 @@ func initTargetEnvsAndApps(_ *cobra.Command, args []string) (err error) {
 	switch onlyPrint {
 	case "":
 		// not set, proceed to render
+		if explain && smartModeSelection {
+			logSmartModeReasons(envAppMap, getGlobe().SmartModeReasons())
+		}
 	case outputFormatJSON:
-		out, err := json.MarshalIndent(envAppMap, "", "  ")
+		var data any = envAppMap
+		if explain {
+			data = explainEnvAppMap(envAppMap, getGlobe().SmartModeReasons())
+		}
+		out, err := json.MarshalIndent(data, "", "  ")
 		if err != nil {
 			return fmt.Errorf("unable to marshal env/app map to JSON: %w", err)
 		}
@@ -140,9 +130,110 @@ func initTargetEnvsAndApps(_ *cobra.Command, args []string) (err error) {
 					fmt.Printf("    %s\n", app)
 				}
 			}
+			if explain {
+				printSmartModeReasons(getGlobe().SmartModeReasons().For(env))
+			}
 		}
+		printSmartModeDeletions(getGlobe())
 		os.Exit(0)
 	}
 
 	return nil
 }
+
+// parseEnvAppArgs builds the map of environments and applications from one or two positional arguments.
+// Returns nil if the only argument is ALL.
+func parseEnvAppArgs(g *myks.Globe, args []string) myks.EnvAppMap {
+	var appNames []string
+	if len(args) > 1 && args[1] != allEnvsToken {
+		appNames = strings.Split(args[1], ",")
+	}
+	if args[0] == allEnvsToken {
+		if len(args) == 1 {
+			return nil
+		}
+		return myks.EnvAppMap{g.EnvironmentBaseDir: appNames}
+	}
+
+	envAppMap := make(myks.EnvAppMap)
+	for env := range strings.SplitSeq(args[0], ",") {
+		// Resolve environment ID to path if needed
+		resolvedEnv := g.ResolveEnvIdentifier(env)
+		envAppMap[resolvedEnv] = appNames
+	}
+	return envAppMap
+}
+
+// explainedEnvApps is the JSON output of an environment selected by Smart Mode with --smart-mode.explain.
+type explainedEnvApps struct {
+	Apps    []string               `json:"apps"`
+	Reasons []myks.SmartModeReason `json:"reasons"`
+}
+
+func explainEnvAppMap(envAppMap myks.EnvAppMap, reasons myks.SmartModeReasons) map[string]explainedEnvApps {
+	explained := make(map[string]explainedEnvApps, len(envAppMap))
+	for env, apps := range envAppMap {
+		envReasons := reasons.For(env)
+		if envReasons == nil {
+			envReasons = []myks.SmartModeReason{}
+		}
+		explained[env] = explainedEnvApps{Apps: apps, Reasons: envReasons}
+	}
+	return explained
+}
+
+// printSmartModeReasons prints the reasons of a Smart Mode selection, one per line.
+func printSmartModeReasons(reasons []myks.SmartModeReason) {
+	for _, reason := range reasons {
+		line := reason.Rule
+		if reason.App != "" {
+			line = reason.App + ": " + line
+		}
+		if reason.File != "" {
+			line += " " + reason.File
+		}
+		if reason.Library != "" {
+			line += " (loads " + reason.Library + ")"
+		}
+		fmt.Printf("      %s %s", aurora.Faint("↳"), line)
+		if reason.Pattern != "" {
+			fmt.Printf(" %s", aurora.Faint(reason.Pattern))
+		}
+		fmt.Println()
+	}
+}
+
+// logSmartModeReasons logs the reasons of a Smart Mode selection, so they are visible in the output of a render.
+func logSmartModeReasons(envAppMap myks.EnvAppMap, reasons myks.SmartModeReasons) {
+	for _, env := range slices.Sorted(maps.Keys(envAppMap)) {
+		for _, reason := range reasons.For(env) {
+			log.Info().
+				Str("env", env).
+				Str("app", reason.App).
+				Str("rule", reason.Rule).
+				Str("file", reason.File).
+				Str("library", reason.Library).
+				Str("pattern", reason.Pattern).
+				Msg("Smart Mode selected")
+		}
+	}
+}
+
+// printSmartModeDeletions prints the rendered environments and applications that would be removed.
+func printSmartModeDeletions(g *myks.Globe) {
+	deletions := g.SmartModeDeletions()
+	if len(deletions) == 0 {
+		return
+	}
+	fmt.Println(aurora.Bold("\nSmart Mode would clean up:"))
+	for _, envID := range slices.Sorted(maps.Keys(deletions)) {
+		fmt.Printf("→ %s\n", envID)
+		if deletions[envID] == nil {
+			fmt.Println(aurora.Bold("    ALL"))
+			continue
+		}
+		for _, app := range deletions[envID] {
+			fmt.Printf("    %s\n", app)
+		}
+	}
+}
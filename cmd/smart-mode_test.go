@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/myks"
 )
 
 func Test_normalizeOnlyPrint(t *testing.T) {
@@ -66,3 +69,17 @@ func Test_normalizeOnlyPrint(t *testing.T) {
 		})
 	}
 }
+
+func Test_explainEnvAppMap(t *testing.T) {
+	reasons := myks.SmartModeReasons{
+		"envs/group/env1": {{App: "app1", File: "envs/group/env1/_apps/app1/ytt/a.yaml", Rule: "app"}},
+	}
+	explained := explainEnvAppMap(myks.EnvAppMap{"envs/group": nil, "envs/env2": {"app2"}}, reasons)
+
+	data, err := json.Marshal(explained)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "envs/group": {"apps": null, "reasons": [{"app": "app1", "file": "envs/group/env1/_apps/app1/ytt/a.yaml", "rule": "app"}]},
+  "envs/env2": {"apps": ["app2"], "reasons": []}
+}`, string(data))
+}
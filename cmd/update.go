@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+const updateCmdLongHelp = `Update the version of a vendir source and re-render the affected applications.
+
+The source is selected by its name as reported by "myks outdated": the chart name for helm charts,
+the repository URL for git sources, or the repository for images and imgpkg bundles.
+The vendor path of the source, e.g. charts/nginx, can be used as well.
+
+The version is rewritten in place in the vendir config or ytt data values file that defines it,
+preserving comments and formatting. When the version is defined in a prototype, the change applies
+to every application of the prototype that doesn't override it.
+
+After updating, the affected applications within the selected environments and applications
+are synced and rendered. Applications outside the selection are rendered by the next "myks render".`
+
+func newUpdateCmd() *cobra.Command {
+	var source, to string
+
+	cmd := &cobra.Command{
+		Use:               "update [env-selector [app-selector]] --source <name> [--to <version|latest>]",
+		Short:             "Update the version of a vendir source",
+		Long:              updateCmdLongHelp,
+		Args:              cobra.MaximumNArgs(2),
+		ValidArgsFunction: shellCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return UpdateCmd(getGlobe(), parseInspectEnvAppMap(args), source, to)
+		},
+	}
+
+	cmd.Flags().StringVar(&source, "source", "", "name or vendor path of the source to update")
+	cmd.Flags().StringVar(&to, "to", myks.UpdateToLatest, `target version, or "latest" for the latest semantic version`)
+	if err := cmd.MarkFlagRequired("source"); err != nil {
+		log.Fatal().Err(err).Msg("Unable to mark flag as required")
+	}
+	cmd.SetUsageTemplate(inspectAppsUsageTemplate)
+	return cmd
+}
+
+// UpdateCmd rewrites the version of a vendir source and re-renders the affected applications.
+func UpdateCmd(g *myks.Globe, envAppMap myks.EnvAppMap, source, to string) error {
+	if source == "" {
+		return errors.New("source must not be empty")
+	}
+	if err := g.ValidateRootDir(); err != nil {
+		return fmt.Errorf("root directory is not suitable for myks: %w", err)
+	}
+	if err := g.Init(asyncLevel, envAppMap); err != nil {
+		return fmt.Errorf("unable to initialize myks' globe: %w", err)
+	}
+
+	resolver := myks.NewVersionResolver()
+	if err := resolver.LoadCredentials(g); err != nil {
+		return fmt.Errorf("unable to load vendir secrets: %w", err)
+	}
+	updates, err := g.UpdateSources(asyncLevel, envAppMap, resolver, source, to)
+	if err != nil {
+		return fmt.Errorf("updating source %s failed: %w", source, err)
+	}
+	if len(updates) == 0 {
+		log.Info().Str("source", source).Msg("Nothing to update")
+		return nil
+	}
+	printSourceUpdates(updates)
+
+	if err := g.Run(asyncLevel, true, true); err != nil {
+		return fmt.Errorf("run failed: %w", err)
+	}
+	return nil
+}
+
+func printSourceUpdates(updates []myks.SourceUpdate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ENV\tAPP\tPATH\tFROM\tTO\tFILE")
+	for _, u := range updates {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", u.Env, u.App, u.Path, u.From, u.To, u.File)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print updates: %v\n", err)
+	}
+}
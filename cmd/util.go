@@ -21,6 +21,27 @@ func getGlobe() *myks.Globe {
 		if err := viper.UnmarshalKey("naming-conventions", globe); err != nil {
 			log.Error().Err(err).Msg("Unable to unmarshal naming-conventions config")
 		}
+		cacheDir, err := myks.ResolveSharedVendirCacheDir(viper.GetString("vendir-cache-dir"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to resolve vendir-cache-dir")
+		}
+		globe.SharedVendirCacheDir = cacheDir
+		if err := viper.UnmarshalKey("vendir-credentials", &globe.CredentialSources); err != nil {
+			log.Fatal().Err(err).Msg("Unable to unmarshal vendir-credentials config")
+		}
+		if err := viper.UnmarshalKey("sync.mirrors", &globe.SyncMirrors); err != nil {
+			log.Fatal().Err(err).Msg("Unable to unmarshal sync.mirrors config")
+		}
+		if err := viper.UnmarshalKey("sync", &globe.SyncSchedule); err != nil {
+			log.Fatal().Err(err).Msg("Unable to unmarshal sync config")
+		}
+		if err := viper.UnmarshalKey("smart-mode.rules", &globe.SmartModeRules); err != nil {
+			log.Fatal().Err(err).Msg("Unable to unmarshal smart-mode.rules config")
+		}
+		if err := viper.UnmarshalKey("hooks", &globe.Hooks); err != nil {
+			log.Fatal().Err(err).Msg("Unable to unmarshal hooks config")
+		}
+		globe.Plugins = plugins
 	}
 	return globe
 }
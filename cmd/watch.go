@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	aurora "github.com/logrusorgru/aurora/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/mykso/myks/internal/myks"
+)
+
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch [environments [applications]]",
+		Short: "Re-render affected applications on file changes",
+		Long: `Watch the project files and re-render the applications affected by changes until interrupted.
+
+Changes are classified by the Smart Mode rules, so that only the affected applications are rendered again.
+File changes are collected until no file changes for the debounce period.
+
+Environments and applications are selected like with the render command, all of them by default.
+Changes outside of the selection are ignored.`,
+		Args: cobra.RangeArgs(0, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			debounce, err := cmd.Flags().GetDuration("debounce")
+			okOrFatal(err, "Failed to read flag")
+			render, _ := readFlagBool(cmd, "render")
+
+			g := getGlobe()
+			okOrFatal(g.ValidateRootDir(), "Root directory is not suitable for myks")
+			var scope myks.EnvAppMap
+			if len(args) > 0 {
+				scope = parseEnvAppArgs(g, args)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			out := cmd.OutOrStdout()
+			err = g.Watch(ctx, myks.WatchOptions{
+				AsyncLevel: asyncLevel,
+				Debounce:   debounce,
+				Scope:      scope,
+				Sync:       !render,
+				OnBatch:    func(batch myks.WatchBatch) { printWatchBatch(out, batch) },
+			})
+			okOrFatal(err, "Watching failed")
+		},
+		ValidArgsFunction: shellCompletion,
+	}
+
+	watchCmd.Flags().Duration("debounce", 500*time.Millisecond, "wait for no file changes for this duration before re-rendering")
+	watchCmd.Flags().BoolP("render", "r", false, "only render manifests, without syncing external sources")
+
+	return watchCmd
+}
+
+// printWatchBatch prints a compact status of a re-render, one line per application.
+func printWatchBatch(w io.Writer, batch myks.WatchBatch) {
+	header := fmt.Sprintf("%s %d changed files", time.Now().Format(time.TimeOnly), len(batch.Changed))
+	switch {
+	case batch.Err != nil:
+		_, _ = fmt.Fprintf(w, "%s: %s %v\n", header, aurora.Red("failed"), batch.Err)
+	case len(batch.Selection) == 0:
+		_, _ = fmt.Fprintf(w, "%s: %s\n", header, aurora.Faint("no affected applications"))
+	default:
+		_, _ = fmt.Fprintf(w, "%s: %d applications rendered\n", header, len(batch.Apps))
+	}
+	for _, app := range batch.Apps {
+		status := aurora.Green("ok")
+		if app.Err != nil {
+			status = aurora.Red("failed")
+		}
+		_, _ = fmt.Fprintf(w, "  %-6s %s/%s %s\n", status, app.EnvID, app.App, aurora.Faint(app.Duration.Round(time.Millisecond)))
+	}
+}
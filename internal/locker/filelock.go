@@ -0,0 +1,56 @@
+package locker
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxLockFileNameLen limits the readable part of lock file names.
+const maxLockFileNameLen = 64
+
+// lockFileName maps a lock name to a file name. The readable prefix helps debugging,
+// the hash keeps names unique after sanitizing and truncating.
+func lockFileName(name string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	readable := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if len(readable) > maxLockFileNameLen {
+		readable = readable[len(readable)-maxLockFileNameLen:]
+	}
+	return fmt.Sprintf("%s-%x.lock", readable, h.Sum64())
+}
+
+// lockFile acquires a shared or exclusive lock on the lock file of a name, blocking until it is available.
+func (l *Locker) lockFile(name string, forWrite bool) (*os.File, error) {
+	if err := os.MkdirAll(l.dir, 0o750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, lockFileName(name)), os.O_CREATE|os.O_RDWR, 0o600) // #nosec G304 -- the file name is sanitized
+	if err != nil {
+		return nil, err
+	}
+	if err = flock(f, forWrite); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("locking %s: %w", f.Name(), err)
+	}
+	return f, nil
+}
+
+// unlockFile releases the lock on a lock file. The file itself is kept, removing it would race with other processes.
+func unlockFile(f *os.File) {
+	if f == nil {
+		return
+	}
+	_ = funlock(f)
+	_ = f.Close()
+}
@@ -0,0 +1,122 @@
+package locker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+// Two lockers sharing a directory behave like two processes: they don't share
+// in-process mutexes and coordinate via lock files only.
+
+// TestFileLockerWriterBlocksOtherLocker verifies that a write lock held by one
+// locker blocks another locker using the same lock directory.
+func TestFileLockerWriterBlocksOtherLocker(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lk1 := locker.NewFileLocker(dir)
+	lk2 := locker.NewFileLocker(dir)
+
+	writeUnlock := lk1.Acquire([]locker.LockReq{{Name: "cache/entry", ForWrite: true}})
+
+	readDone := make(chan struct{})
+	go func() {
+		unlock := lk2.Acquire([]locker.LockReq{{Name: "cache/entry", ForWrite: false}})
+		unlock()
+		close(readDone)
+	}()
+
+	// Give the reader time to block on the lock file.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-readDone:
+		t.Fatal("reader of another locker should be blocked while the writer holds the lock")
+	default:
+	}
+
+	writeUnlock()
+	select {
+	case <-readDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader did not proceed after the writer released the lock")
+	}
+
+	snap := lk2.GetStats().Snapshot()
+	require.Contains(t, snap, "cache/entry")
+	assert.GreaterOrEqual(t, snap["cache/entry"].MaxWaitTime, 40*time.Millisecond, "waiting for the lock file is recorded")
+	assert.Empty(t, lk2.GetStats().FileLockErrors())
+}
+
+// TestFileLockerReadersDontBlockEachOther verifies that read locks of different
+// lockers are shared.
+func TestFileLockerReadersDontBlockEachOther(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lk1 := locker.NewFileLocker(dir)
+	lk2 := locker.NewFileLocker(dir)
+
+	unlock1 := lk1.Acquire([]locker.LockReq{{Name: "item", ForWrite: false}})
+	defer unlock1()
+
+	done := make(chan struct{})
+	go func() {
+		unlock := lk2.Acquire([]locker.LockReq{{Name: "item", ForWrite: false}})
+		unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("readers of different lockers should not block each other")
+	}
+}
+
+// TestFileLockerFallsBackToInProcessLocking verifies that a failure to create
+// lock files is recorded in stats and doesn't prevent locking.
+func TestFileLockerFallsBackToInProcessLocking(t *testing.T) {
+	t.Parallel()
+
+	notADir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0o600))
+
+	lk := locker.NewFileLocker(notADir)
+	unlock := lk.Acquire([]locker.LockReq{{Name: "item", ForWrite: true}})
+	unlock()
+
+	assert.Len(t, lk.GetStats().FileLockErrors(), 1)
+	assert.Contains(t, lk.GetStats().BuildSummary(0), "Cross-process lock failures: 1")
+}
+
+// TestFileLockerLockFileNames verifies that lock names with path separators
+// are stored as flat, distinct files in the lock directory.
+func TestFileLockerLockFileNames(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lk := locker.NewFileLocker(dir)
+	unlock := lk.LockNames(func(yield func(string) bool) {
+		for _, name := range []string{"app:/root/project/.myks/envs/a", "app:/root/project/.myks/envs_a"} {
+			if !yield(name) {
+				return
+			}
+		}
+	}, true)
+	unlock()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "sanitized names must not collide")
+	for _, entry := range entries {
+		assert.False(t, entry.IsDir())
+		assert.Equal(t, ".lock", filepath.Ext(entry.Name()))
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package locker
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func flock(f *os.File, exclusive bool) error {
+	how := unix.LOCK_SH
+	if exclusive {
+		how = unix.LOCK_EX
+	}
+	for {
+		err := unix.Flock(int(f.Fd()), how) // #nosec G115 -- file descriptor fits in int
+		if !errors.Is(err, unix.EINTR) {
+			return err
+		}
+	}
+}
+
+func funlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN) // #nosec G115 -- file descriptor fits in int
+}
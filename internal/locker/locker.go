@@ -1,9 +1,11 @@
 // Package locker provides a per-item RWMutex registry for coordinating
 // concurrent read and write access to items across goroutines.
+// Optionally, items are also locked across processes with lock files.
 package locker
 
 import (
 	"iter"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -14,6 +16,8 @@ type Locker struct {
 	mu    sync.Mutex
 	locks map[string]*sync.RWMutex
 	stats Stats
+	// Directory with lock files, cross-process locking is disabled if empty
+	dir string
 }
 
 // LockReq describes what kind of access is needed for an item.
@@ -27,6 +31,14 @@ func NewLocker() *Locker {
 	return &Locker{locks: make(map[string]*sync.RWMutex), stats: newStats()}
 }
 
+// NewFileLocker creates a Locker that additionally locks items across processes
+// using lock files in dir. Lock files are created on demand and never removed.
+func NewFileLocker(dir string) *Locker {
+	l := NewLocker()
+	l.dir = dir
+	return l
+}
+
 // GetStats returns a pointer to the Locker's statistics tracker.
 func (l *Locker) GetStats() *Stats {
 	return &l.stats
@@ -59,8 +71,11 @@ func (l *Locker) Acquire(reqs []LockReq) (unlock func()) {
 
 	// acquireTimes records when each lock was actually acquired (after waiting).
 	acquireTimes := make([]time.Time, len(deduped))
+	// files holds the cross-process lock files, nil if not used or failed to lock.
+	files := make([]*os.File, len(deduped))
 
 	// Acquire in sorted order, recording wait and acquisition time per lock.
+	// The in-process lock is taken first, so goroutines of one process don't compete for the lock file.
 	for i, req := range deduped {
 		lk := l.getOrCreate(req.Name)
 		waitStart := time.Now()
@@ -69,6 +84,14 @@ func (l *Locker) Acquire(reqs []LockReq) (unlock func()) {
 		} else {
 			lk.RLock()
 		}
+		if l.dir != "" {
+			f, err := l.lockFile(req.Name, req.ForWrite)
+			if err != nil {
+				// Fall back to in-process locking, the failure is reported in the stats
+				l.stats.recordFileLockError(err)
+			}
+			files[i] = f
+		}
 		acquireTimes[i] = time.Now()
 		l.stats.recordAcquire(req.Name, req.ForWrite, time.Since(waitStart))
 	}
@@ -77,6 +100,7 @@ func (l *Locker) Acquire(reqs []LockReq) (unlock func()) {
 	return func() {
 		for i := len(deduped) - 1; i >= 0; i-- {
 			l.stats.recordRelease(deduped[i].Name, time.Since(acquireTimes[i]))
+			unlockFile(files[i])
 			lk := l.getOrCreate(deduped[i].Name)
 			if deduped[i].ForWrite {
 				lk.Unlock()
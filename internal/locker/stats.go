@@ -2,6 +2,7 @@ package locker
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -24,6 +25,8 @@ type LockStat struct {
 type Stats struct {
 	mu    sync.Mutex
 	stats map[string]*LockStat
+	// Cross-process lock failures, the affected items were locked in-process only
+	fileLockErrors []error
 }
 
 func newStats() Stats {
@@ -67,6 +70,20 @@ func (s *Stats) recordRelease(name string, holdDur time.Duration) {
 	}
 }
 
+func (s *Stats) recordFileLockError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fileLockErrors = append(s.fileLockErrors, err)
+}
+
+// FileLockErrors returns the errors of cross-process locking.
+// Items affected by these errors were only locked within the process.
+func (s *Stats) FileLockErrors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.fileLockErrors)
+}
+
 // Snapshot returns a safe copy of current stats keyed by lock name.
 func (s *Stats) Snapshot() map[string]LockStat {
 	s.mu.Lock()
@@ -86,6 +103,7 @@ func (s *Stats) BuildSummary(wallClock time.Duration) string {
 	if len(snap) == 0 {
 		return ""
 	}
+	fileLockErrors := len(s.FileLockErrors())
 
 	names := make([]string, 0, len(snap))
 	for k := range snap {
@@ -133,6 +151,9 @@ func (s *Stats) BuildSummary(wallClock time.Duration) string {
 	} else {
 		fmt.Fprintf(&sb, "Total lock wait: %s\n", totalWait.Round(time.Millisecond).String())
 	}
+	if fileLockErrors > 0 {
+		fmt.Fprintf(&sb, "Cross-process lock failures: %d (locked within the process only)\n", fileLockErrors)
+	}
 
 	return sb.String()
 }
@@ -1,10 +1,10 @@
 package myks
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -109,7 +109,7 @@ func (a *Application) expandServicePath(path string) string {
 }
 
 func (a *Application) expandVendirCache(path string) string {
-	return filepath.Join(a.cfg.RootDir, a.cfg.ServiceDirName, a.cfg.VendirCache, path)
+	return filepath.Join(a.cfg.vendirCacheDir(), path)
 }
 
 func (a *Application) expandVendorPath(path string) string {
@@ -152,7 +152,11 @@ func (a *Application) Msg(step, msg string) string {
 }
 
 func (a *Application) runCmd(step, purpose, cmd string, stdin io.Reader, args []string) (CmdResult, error) {
-	return runCmd(step, cmd, stdin, args, func(cmd string, err error, stderr string, args []string) {
+	return a.runCmdContext(context.Background(), step, purpose, cmd, stdin, args)
+}
+
+func (a *Application) runCmdContext(ctx context.Context, step, purpose, cmd string, stdin io.Reader, args []string) (CmdResult, error) {
+	return runCmdContext(ctx, step, cmd, stdin, args, func(cmd string, err error, stderr string, args []string) {
 		cmd = msgRunCmd(purpose, cmd, args)
 		a.logCmd(step, cmd, err, stderr)
 	})
@@ -219,32 +223,3 @@ func (a *Application) yttS(step, purpose string, paths []string, stdin io.Reader
 func (a *Application) prototypeDirName() string {
 	return strings.TrimPrefix(a.Prototype, a.cfg.PrototypesDir+string(filepath.Separator))
 }
-
-func (a *Application) getHelmChartsDirs(stepName string) ([]string, error) {
-	chartsDirs := []string{}
-	baseDir := a.expandVendorPath(a.cfg.HelmChartsDirName)
-	if ok, err := isExist(baseDir); err != nil {
-		return nil, err
-	} else if !ok {
-		log.Debug().Msg(a.Msg(stepName, "No Helm charts found"))
-		return nil, nil
-	}
-	files, err := os.ReadDir(baseDir)
-	if err != nil {
-		return nil, err
-	}
-	if len(files) == 0 {
-		log.Debug().Msg(a.Msg(stepName, "No Helm charts found"))
-		return nil, nil
-	}
-	for _, file := range files {
-		chartDir := filepath.Join(baseDir, file.Name())
-		if err = ensureValidChartEntry(chartDir); err != nil {
-			log.Warn().Err(err).Msg(a.Msg(stepName, "Skipping invalid chart entry"))
-			continue
-		}
-		chartsDirs = append(chartsDirs, chartDir)
-	}
-
-	return chartsDirs, nil
-}
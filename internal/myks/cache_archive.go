@@ -0,0 +1,269 @@
+package myks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+// CacheImportResult describes the outcome of importing a cache entry.
+type CacheImportResult struct {
+	Name     string `json:"name"`
+	Imported bool   `json:"imported"`
+	// Reason why the entry was not imported, or a warning for imported entries
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExportCache writes the vendir cache entries to w as a gzipped tarball.
+// All entries are exported if no names are given.
+func (g *Globe) ExportCache(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		usage, err := g.collectCacheEntryUsage()
+		if err != nil {
+			return err
+		}
+		for _, u := range usage {
+			names = append(names, u.Name)
+		}
+	}
+	slices.Sort(names)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	cacheDir := g.vendirCacheDir()
+	lock := locker.NewFileLocker(filepath.Join(cacheDir, vendirCacheLocksDirName))
+	for _, name := range names {
+		// Don't export an entry while another myks process is syncing it
+		unlock := lock.LockNames(slices.Values([]string{name}), false)
+		err := ensureCacheDigest(filepath.Join(cacheDir, name))
+		if err == nil {
+			err = addDirToTar(tw, cacheDir, name)
+		}
+		unlock()
+		if err != nil {
+			return fmt.Errorf("exporting cache entry %s: %w", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ensureCacheDigest records the digest of a cache entry that has none, e.g. of the project-local cache,
+// so the entry can be verified on import.
+func ensureCacheDigest(entryDir string) error {
+	if ok, err := isExist(entryDir); err != nil || !ok {
+		return err
+	}
+	if ok, err := isExist(filepath.Join(entryDir, vendirCacheDigestFileName)); err != nil || ok {
+		return err
+	}
+	return recordCacheDigest(entryDir)
+}
+
+// addDirToTar adds the directory baseDir/name to the tarball, with paths relative to baseDir.
+// Symlinks are stored as symlinks, as they are part of the cache entry digest.
+func addDirToTar(tw *tar.Writer, baseDir, name string) error {
+	entryDir := filepath.Join(baseDir, name)
+	if ok, err := isExist(entryDir); err != nil {
+		return err
+	} else if !ok {
+		return ErrCacheEntryNotFound
+	}
+	return filepath.WalkDir(entryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if d.Type()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path) // #nosec G304 -- path is within the cache directory
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// ImportCache reads cache entries from a gzipped tarball created by ExportCache.
+// Entries are verified against their recorded digests before being added to the cache.
+// Existing entries are kept.
+func (g *Globe) ImportCache(r io.Reader) ([]CacheImportResult, error) {
+	cacheDir := g.vendirCacheDir()
+	if err := createDirectory(cacheDir); err != nil {
+		return nil, err
+	}
+	stageDir, err := os.MkdirTemp(cacheDir, ".import-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(stageDir); err != nil {
+			log.Warn().Err(err).Str("dir", stageDir).Msg(g.Msg("Unable to remove temporary import directory"))
+		}
+	}()
+
+	names, err := extractCacheArchive(r, stageDir)
+	if err != nil {
+		return nil, fmt.Errorf("extracting cache archive: %w", err)
+	}
+
+	lock := locker.NewFileLocker(filepath.Join(cacheDir, vendirCacheLocksDirName))
+	results := make([]CacheImportResult, 0, len(names))
+	for _, name := range names {
+		result := CacheImportResult{Name: name}
+		verified := verifyCacheEntryDir(filepath.Join(stageDir, name))
+		switch verified.Status {
+		case CacheStatusOK:
+		case CacheStatusNoDigest:
+			result.Reason = "no digest recorded, imported without verification"
+		case CacheStatusMismatch:
+			result.Reason = fmt.Sprintf("digest mismatch: expected %s, got %s", verified.Expected, verified.Actual)
+			results = append(results, result)
+			continue
+		default:
+			result.Reason = verified.Error
+			results = append(results, result)
+			continue
+		}
+
+		unlock := lock.LockNames(slices.Values([]string{name}), true)
+		target := filepath.Join(cacheDir, name)
+		if ok, err := isExist(target); err != nil {
+			unlock()
+			return nil, err
+		} else if ok {
+			result.Reason = "already exists"
+		} else if err = os.Rename(filepath.Join(stageDir, name), target); err != nil {
+			unlock()
+			return nil, fmt.Errorf("importing cache entry %s: %w", name, err)
+		} else {
+			result.Imported = true
+		}
+		unlock()
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// extractCacheArchive extracts a cache archive to dir and returns the names of the extracted entries.
+// Symlinks pointing outside of their cache entry are skipped.
+func extractCacheArchive(r io.Reader, dir string) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := root.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close directory")
+		}
+	}()
+
+	names := map[string]bool{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		entryName, _, _ := strings.Cut(filepath.ToSlash(name), "/")
+		if strings.HasPrefix(entryName, ".") {
+			log.Debug().Str("path", header.Name).Msg("Skipping service entry in cache archive")
+			continue
+		}
+		names[entryName] = true
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = root.MkdirAll(name, 0o750)
+		case tar.TypeReg:
+			if err = root.MkdirAll(filepath.Dir(name), 0o750); err == nil {
+				err = extractCacheArchiveFile(root, name, header.FileInfo().Mode().Perm(), tr)
+			}
+		case tar.TypeSymlink:
+			// The target must stay within the cache entry
+			relToEntry := strings.TrimPrefix(filepath.ToSlash(name), entryName+"/")
+			target := filepath.Join(filepath.Dir(filepath.FromSlash(relToEntry)), filepath.FromSlash(header.Linkname))
+			if filepath.IsAbs(header.Linkname) || !filepath.IsLocal(target) {
+				log.Warn().Str("path", header.Name).Str("target", header.Linkname).Msg("Skipping symlink pointing outside of the cache entry")
+				continue
+			}
+			if err = root.MkdirAll(filepath.Dir(name), 0o750); err == nil {
+				err = root.Symlink(header.Linkname, name)
+			}
+		default:
+			log.Debug().Str("path", header.Name).Msg("Skipping unsupported cache archive entry")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extracting %s: %w", header.Name, err)
+		}
+	}
+	return slices.Sorted(maps.Keys(names)), nil
+}
+
+func extractCacheArchiveFile(root *os.Root, name string, perm fs.FileMode, r io.Reader) error {
+	out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r) // #nosec G110 -- the archive is provided by the user
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
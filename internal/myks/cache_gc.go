@@ -0,0 +1,242 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+// File in a cache entry, its modification time is the last time the entry was used
+const vendirCacheLastUsedFileName = "myks-last-used"
+
+// CacheEntryUsage describes the usage of a vendir cache entry.
+type CacheEntryUsage struct {
+	Name     string
+	LastUsed time.Time
+	Size     int64
+}
+
+// touchCacheEntry records the current time as the last usage of a cache entry.
+func touchCacheEntry(a *Application, cacheName string) {
+	path := filepath.Join(a.expandVendirCache(cacheName), vendirCacheLastUsedFileName)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); errors.Is(err, fs.ErrNotExist) {
+		err = writeFile(path, nil)
+		if err != nil {
+			log.Debug().Err(err).Str("cache", cacheName).Msg("Unable to record cache entry usage")
+		}
+	} else if err != nil {
+		log.Debug().Err(err).Str("cache", cacheName).Msg("Unable to record cache entry usage")
+	}
+}
+
+// collectCacheEntryUsage returns the last usage time and the size of every vendir cache entry.
+// Entries used before the usage was recorded fall back to the modification time of the entry directory.
+func (g *Globe) collectCacheEntryUsage() ([]CacheEntryUsage, error) {
+	cacheDir := g.vendirCacheDir()
+	entries, err := os.ReadDir(cacheDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %w", err)
+	}
+
+	var usage []CacheEntryUsage
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		u, err := readCacheEntryUsage(cacheDir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+func readCacheEntryUsage(cacheDir, name string) (CacheEntryUsage, error) {
+	entryDir := filepath.Join(cacheDir, name)
+	info, err := os.Stat(filepath.Join(entryDir, vendirCacheLastUsedFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		info, err = os.Stat(entryDir)
+	}
+	if err != nil {
+		return CacheEntryUsage{}, err
+	}
+	size, err := directorySize(entryDir)
+	if err != nil {
+		return CacheEntryUsage{}, fmt.Errorf("computing size of %s: %w", entryDir, err)
+	}
+	return CacheEntryUsage{Name: name, LastUsed: info.ModTime(), Size: size}, nil
+}
+
+// selectCacheEvictions returns the entries to evict: entries not used within maxAge,
+// then least recently used entries until the total size fits maxSize. Zero limits are ignored.
+func selectCacheEvictions(entries []CacheEntryUsage, now time.Time, maxAge time.Duration, maxSize int64) []CacheEntryUsage {
+	sorted := slices.Clone(entries)
+	slices.SortFunc(sorted, func(x, y CacheEntryUsage) int {
+		if c := x.LastUsed.Compare(y.LastUsed); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Name, y.Name)
+	})
+
+	var totalSize int64
+	for _, entry := range sorted {
+		totalSize += entry.Size
+	}
+
+	var evicted []CacheEntryUsage
+	for _, entry := range sorted {
+		expired := maxAge > 0 && now.Sub(entry.LastUsed) > maxAge
+		oversized := maxSize > 0 && totalSize > maxSize
+		if !expired && !oversized {
+			// Entries are sorted by usage, the rest is newer
+			break
+		}
+		evicted = append(evicted, entry)
+		totalSize -= entry.Size
+	}
+	return evicted
+}
+
+// CleanupCacheByUsage evicts vendir cache entries not used within maxAge and,
+// if the cache is larger than maxSize, least recently used entries. Zero limits are ignored.
+// Unlike CleanupObsoleteCacheEntries, entries referenced by other branches or projects may be evicted;
+// they are synced again when needed.
+func (g *Globe) CleanupCacheByUsage(maxAge time.Duration, maxSize int64, dryRun bool) error {
+	usage, err := g.collectCacheEntryUsage()
+	if err != nil {
+		return err
+	}
+
+	lock := locker.NewFileLocker(filepath.Join(g.vendirCacheDir(), vendirCacheLocksDirName))
+	var freed int64
+	for _, entry := range selectCacheEvictions(usage, time.Now(), maxAge, maxSize) {
+		freed += g.evictCacheEntry(lock, entry, dryRun)
+	}
+	if dryRun {
+		log.Info().Str("wouldFree", FormatByteSize(freed)).Msg(g.Msg("Cache garbage collection finished (dry run)"))
+		return nil
+	}
+	log.Info().Str("freed", FormatByteSize(freed)).Msg(g.Msg("Cache garbage collection finished"))
+	return nil
+}
+
+// evictCacheEntry removes a cache entry selected for eviction and returns the number of bytes freed.
+// The entry is kept if it was used or removed by a concurrent myks run since the usage was collected.
+func (g *Globe) evictCacheEntry(lock *locker.Locker, entry CacheEntryUsage, dryRun bool) int64 {
+	cacheDir := g.vendirCacheDir()
+	entryDir := filepath.Join(cacheDir, entry.Name)
+	// Don't remove an entry while another myks process is using it
+	unlock := lock.LockNames(slices.Values([]string{entry.Name}), true)
+	defer unlock()
+
+	current, err := readCacheEntryUsage(cacheDir, entry.Name)
+	if err != nil {
+		log.Debug().Err(err).Str("dir", entryDir).Msg(g.Msg("Skipping eviction of cache entry"))
+		return 0
+	}
+	if current.LastUsed.After(entry.LastUsed) {
+		log.Debug().Str("dir", entryDir).Time("lastUsed", current.LastUsed).Msg(g.Msg("Skipping eviction of cache entry used meanwhile"))
+		return 0
+	}
+	if dryRun {
+		log.Info().Str("path", entryDir).Msg(g.Msg("Would evict cache entry"))
+		return current.Size
+	}
+	log.Debug().Str("dir", entryDir).Time("lastUsed", current.LastUsed).Int64("size", current.Size).Msg(g.Msg("Evicting cache entry"))
+	if err := os.RemoveAll(entryDir); err != nil {
+		log.Warn().Err(err).Str("path", entryDir).Msg(g.Msg("Failed to remove cache entry"))
+		return 0
+	}
+	return current.Size
+}
+
+func directorySize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ParseAge parses a duration that additionally supports days and weeks, e.g. "30d" or "2w".
+func ParseAge(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return d, nil
+}
+
+var byteSizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	// Longer suffixes first, so "GiB" is not matched as "B"
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size with an optional decimal (KB, MB, ...) or binary (KiB, MiB, ...) unit, e.g. "10GiB".
+func ParseByteSize(value string) (int64, error) {
+	number, factor := strings.TrimSpace(value), 1.0
+	for _, unit := range byteSizeUnits {
+		if n, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, factor = strings.TrimSpace(n), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(n * factor), nil
+}
+
+// FormatByteSize formats a size with a binary unit, e.g. "1.5 GiB".
+func FormatByteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,151 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+func TestSelectCacheEvictions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	entries := []CacheEntryUsage{
+		{Name: "recent", LastUsed: now.Add(-1 * day), Size: 100},
+		{Name: "old", LastUsed: now.Add(-40 * day), Size: 10},
+		{Name: "week", LastUsed: now.Add(-7 * day), Size: 50},
+		{Name: "yesterday", LastUsed: now.Add(-2 * day), Size: 200},
+	}
+	names := func(entries []CacheEntryUsage) []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		want    []string
+	}{
+		{"no limits", 0, 0, nil},
+		{"max age", 30 * day, 0, []string{"old"}},
+		{"max size evicts least recently used", 0, 300, []string{"old", "week"}},
+		{"max size already fits", 0, 360, nil},
+		{"both limits", 5 * day, 1000, []string{"old", "week"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, names(selectCacheEvictions(entries, now, tt.maxAge, tt.maxSize)))
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]time.Duration{
+		"30d":   30 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"1.5d":  36 * time.Hour,
+		"12h":   12 * time.Hour,
+		"90m":   90 * time.Minute,
+		"0d":    0,
+		"1h30m": 90 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := ParseAge(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "d", "-1d", "30 days", "abc"} {
+		_, err := ParseAge(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int64{
+		"1024":    1024,
+		"10GiB":   10 << 30,
+		"10 GiB":  10 << 30,
+		"500MB":   500_000_000,
+		"1.5KiB":  1536,
+		"2G":      2 << 30,
+		"100B":    100,
+		"0":       0,
+		"1TB":     1_000_000_000_000,
+		"0.5 MiB": 512 << 10,
+	}
+	for value, want := range tests {
+		got, err := ParseByteSize(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "GiB", "-1MB", "10XB"} {
+		_, err := ParseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "512 B", FormatByteSize(512))
+	assert.Equal(t, "1.5 KiB", FormatByteSize(1536))
+	assert.Equal(t, "10.0 GiB", FormatByteSize(10<<30))
+}
+
+func TestGlobe_CleanupCacheByUsage(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, "", "fresh", "stale", "untracked")
+	app := g.collectAllApplications()[0]
+	touchCacheEntry(app, "fresh")
+	touchCacheEntry(app, "stale")
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(app.expandVendirCache("stale"), vendirCacheLastUsedFileName), old, old))
+	// Entries without recorded usage fall back to the directory modification time
+	require.NoError(t, os.Chtimes(app.expandVendirCache("untracked"), old, old))
+
+	usage, err := g.collectCacheEntryUsage()
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+	for _, entry := range usage {
+		assert.Positive(t, entry.Size, entry.Name)
+	}
+
+	require.NoError(t, g.CleanupCacheByUsage(30*24*time.Hour, 0, true))
+	assert.DirExists(t, app.expandVendirCache("stale"), "dry run does not remove entries")
+
+	// An entry used by a concurrent run after the usage was collected is kept
+	lock := locker.NewFileLocker(filepath.Join(g.vendirCacheDir(), vendirCacheLocksDirName))
+	var stale CacheEntryUsage
+	for _, entry := range usage {
+		if entry.Name == "stale" {
+			stale = entry
+		}
+	}
+	touchCacheEntry(app, "stale")
+	assert.Zero(t, g.evictCacheEntry(lock, stale, false))
+	assert.DirExists(t, app.expandVendirCache("stale"))
+	require.NoError(t, os.Chtimes(filepath.Join(app.expandVendirCache("stale"), vendirCacheLastUsedFileName), old, old))
+
+	require.NoError(t, g.CleanupCacheByUsage(30*24*time.Hour, 0, false))
+	assert.DirExists(t, app.expandVendirCache("fresh"))
+	assert.NoDirExists(t, app.expandVendirCache("stale"))
+	assert.NoDirExists(t, app.expandVendirCache("untracked"))
+	assert.DirExists(t, filepath.Join(g.vendirCacheDir(), vendirCacheLocksDirName), "service entries are kept")
+}
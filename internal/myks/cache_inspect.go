@@ -0,0 +1,248 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"sigs.k8s.io/yaml"
+)
+
+// Statuses of a verified cache entry
+const (
+	CacheStatusOK       = "ok"
+	CacheStatusMismatch = "mismatch"
+	CacheStatusNoDigest = "no-digest"
+	CacheStatusError    = "error"
+)
+
+// ErrCacheEntryNotFound is returned when a requested cache entry does not exist.
+var ErrCacheEntryNotFound = errors.New("cache entry not found")
+
+// CacheEntry describes a vendir cache entry and the applications using it.
+type CacheEntry struct {
+	Name     string    `json:"name"`
+	Dir      string    `json:"dir"`
+	Type     string    `json:"type"`
+	Source   string    `json:"source,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"lastUsed"`
+	Digest   string    `json:"digest,omitempty"`
+	// Applications linking the entry, as recorded in their links maps
+	References []CacheReference `json:"references"`
+	// Rendered vendir config and lock of the entry, only set by InspectCacheEntry
+	VendirConfig string `json:"vendirConfig,omitempty"`
+	VendirLock   string `json:"vendirLock,omitempty"`
+}
+
+// CacheReference is a vendor path of an application linked to a cache entry.
+type CacheReference struct {
+	Env  string `json:"env"`
+	App  string `json:"app"`
+	Path string `json:"path"`
+}
+
+// CacheVerifyResult is the result of verifying a cache entry against its recorded digest.
+type CacheVerifyResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ListCacheEntries returns all vendir cache entries with the initialized applications referencing them.
+func (g *Globe) ListCacheEntries() ([]CacheEntry, error) {
+	usage, err := g.collectCacheEntryUsage()
+	if err != nil {
+		return nil, err
+	}
+	references, err := g.collectCacheReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, 0, len(usage))
+	for _, u := range usage {
+		entries = append(entries, g.describeCacheEntry(u, references[u.Name]))
+	}
+	slices.SortFunc(entries, func(x, y CacheEntry) int { return strings.Compare(x.Name, y.Name) })
+	return entries, nil
+}
+
+// InspectCacheEntry returns a cache entry with its vendir config and lock.
+func (g *Globe) InspectCacheEntry(name string) (CacheEntry, error) {
+	entries, err := g.ListCacheEntries()
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	i := slices.IndexFunc(entries, func(e CacheEntry) bool { return e.Name == name })
+	if i < 0 {
+		return CacheEntry{}, fmt.Errorf("%w: %s", ErrCacheEntryNotFound, name)
+	}
+	entry := entries[i]
+	// Entries of the project-local cache have no recorded digest
+	if entry.Digest == "" {
+		if digest, err := hashDirectory(filepath.Join(entry.Dir, VendirCacheDataDirName)); err == nil {
+			entry.Digest = digest
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(entry.Dir, g.VendirConfigFileName)); err == nil {
+		entry.VendirConfig = string(data)
+	}
+	if data, err := os.ReadFile(filepath.Join(entry.Dir, g.VendirLockFileName)); err == nil {
+		entry.VendirLock = string(data)
+	}
+	return entry, nil
+}
+
+// VerifyCacheEntries recomputes the digests of the cache entries and compares them to the recorded ones.
+// All entries are verified if no names are given.
+func (g *Globe) VerifyCacheEntries(names []string) ([]CacheVerifyResult, error) {
+	if len(names) == 0 {
+		usage, err := g.collectCacheEntryUsage()
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range usage {
+			names = append(names, u.Name)
+		}
+	}
+	slices.Sort(names)
+
+	results := make([]CacheVerifyResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, verifyCacheEntryDir(filepath.Join(g.vendirCacheDir(), name)))
+	}
+	return results, nil
+}
+
+func verifyCacheEntryDir(entryDir string) CacheVerifyResult {
+	result := CacheVerifyResult{Name: filepath.Base(entryDir)}
+	if ok, err := isExist(entryDir); err != nil || !ok {
+		result.Status, result.Error = CacheStatusError, ErrCacheEntryNotFound.Error()
+		return result
+	}
+	actual, err := hashDirectory(filepath.Join(entryDir, VendirCacheDataDirName))
+	if err != nil {
+		result.Status, result.Error = CacheStatusError, err.Error()
+		return result
+	}
+	result.Actual = actual
+	recorded, err := os.ReadFile(filepath.Join(entryDir, vendirCacheDigestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Status = CacheStatusNoDigest
+		return result
+	} else if err != nil {
+		result.Status, result.Error = CacheStatusError, err.Error()
+		return result
+	}
+	result.Expected = strings.TrimSpace(string(recorded))
+	if result.Expected == actual {
+		result.Status = CacheStatusOK
+	} else {
+		result.Status = CacheStatusMismatch
+	}
+	return result
+}
+
+// collectCacheReferences maps cache names to the vendor paths of the initialized applications linking them.
+func (g *Globe) collectCacheReferences() (map[string][]CacheReference, error) {
+	references := map[string][]CacheReference{}
+	for _, app := range g.collectAllApplications() {
+		linksMap, err := app.getLinksMap()
+		if err != nil {
+			return nil, fmt.Errorf("reading links map of %s/%s: %w", app.e.ID, app.Name, err)
+		}
+		for path, cacheName := range linksMap {
+			references[cacheName] = append(references[cacheName], CacheReference{
+				Env:  app.e.ID,
+				App:  app.Name,
+				Path: filepath.ToSlash(path),
+			})
+		}
+	}
+	for _, refs := range references {
+		slices.SortFunc(refs, func(x, y CacheReference) int {
+			return strings.Compare(x.Env+"\x00"+x.App+"\x00"+x.Path, y.Env+"\x00"+y.App+"\x00"+y.Path)
+		})
+	}
+	return references, nil
+}
+
+func (g *Globe) describeCacheEntry(usage CacheEntryUsage, references []CacheReference) CacheEntry {
+	entry := CacheEntry{
+		Name:       usage.Name,
+		Dir:        filepath.Join(g.vendirCacheDir(), usage.Name),
+		Type:       "unknown",
+		Size:       usage.Size,
+		LastUsed:   usage.LastUsed,
+		References: references,
+	}
+	if entry.References == nil {
+		entry.References = []CacheReference{}
+	}
+	if digest, err := os.ReadFile(filepath.Join(entry.Dir, vendirCacheDigestFileName)); err == nil {
+		entry.Digest = strings.TrimSpace(string(digest))
+	}
+
+	entry.Type, entry.Source, entry.Version = describeCacheSource(entry.Dir, g.VendirConfigFileName)
+	return entry
+}
+
+// describeCacheSource returns the source type, location, and version of a cache entry from its vendir config.
+func describeCacheSource(entryDir, vendirConfigFileName string) (sourceType, location, version string) {
+	sourceType = "unknown"
+	data, err := os.ReadFile(filepath.Join(entryDir, vendirConfigFileName)) // #nosec G304 -- path is within the cache directory
+	if err != nil {
+		return sourceType, "", ""
+	}
+	var vendirConfig vendirconf.Config
+	if err = yaml.Unmarshal(data, &vendirConfig); err != nil || len(vendirConfig.Directories) == 0 || len(vendirConfig.Directories[0].Contents) == 0 {
+		return sourceType, "", ""
+	}
+	content := vendirConfig.Directories[0].Contents[0]
+	sourceType = contentType(content)
+	if source, ok := describeContent(content); ok {
+		location, version = source.Location, source.Current
+		if source.Type == SourceTypeHelmChart {
+			location = strings.TrimSuffix(source.Location, "/") + "/" + source.Name
+		}
+	}
+	return sourceType, location, version
+}
+
+// contentType returns the name of the source type of a vendir content, as used in vendir configs.
+func contentType(content vendirconf.DirectoryContents) string { //nolint:gocritic // external type
+	switch {
+	case content.Git != nil:
+		return SourceTypeGit
+	case content.Hg != nil:
+		return "hg"
+	case content.HTTP != nil:
+		return "http"
+	case content.Image != nil:
+		return SourceTypeImage
+	case content.ImgpkgBundle != nil:
+		return SourceTypeImgpkgBundle
+	case content.GithubRelease != nil:
+		return "githubRelease"
+	case content.HelmChart != nil:
+		return SourceTypeHelmChart
+	case content.Directory != nil:
+		return "directory"
+	case content.Manual != nil:
+		return "manual"
+	case content.Inline != nil:
+		return "inline"
+	default:
+		return "unknown"
+	}
+}
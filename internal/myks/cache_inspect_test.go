@@ -0,0 +1,122 @@
+package myks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobe_ListCacheEntries(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, "", "cert-manager", "orphan")
+	app := g.collectAllApplications()[0]
+	vendirConfig := `apiVersion: vendir.k14s.io/v1alpha1
+kind: Config
+directories:
+  - path: charts/cert-manager
+    contents:
+      - path: .
+        helmChart:
+          name: cert-manager
+          version: v1.14.5
+          repository:
+            url: https://charts.jetstack.io/
+`
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("cert-manager"), g.VendirConfigFileName), []byte(vendirConfig)))
+	// Entries not referenced by any application are listed as well
+	require.NoError(t, os.Remove(app.getLinksMapPath()))
+	app.linksMap = map[string]string{"charts/cert-manager": "cert-manager"}
+
+	entries, err := g.ListCacheEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "cert-manager", entries[0].Name)
+	assert.Equal(t, SourceTypeHelmChart, entries[0].Type)
+	assert.Equal(t, "https://charts.jetstack.io/cert-manager", entries[0].Source)
+	assert.Equal(t, "v1.14.5", entries[0].Version)
+	assert.Positive(t, entries[0].Size)
+	assert.Equal(t, []CacheReference{{Env: "env", App: "app", Path: "charts/cert-manager"}}, entries[0].References)
+
+	assert.Equal(t, "orphan", entries[1].Name)
+	assert.Equal(t, "unknown", entries[1].Type)
+	assert.Empty(t, entries[1].References)
+
+	assert.Empty(t, entries[0].Digest, "entries of the project-local cache are not hashed on sync")
+
+	entry, err := g.InspectCacheEntry("cert-manager")
+	require.NoError(t, err)
+	assert.Equal(t, vendirConfig, entry.VendirConfig)
+	assert.NotEmpty(t, entry.Digest, "the digest is computed on inspect")
+
+	_, err = g.InspectCacheEntry("missing")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+}
+
+func TestGlobe_VerifyCacheEntries(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, "", "intact", "corrupted", "legacy")
+	app := g.collectAllApplications()[0]
+	require.NoError(t, recordCacheDigest(app.expandVendirCache("intact")))
+	require.NoError(t, recordCacheDigest(app.expandVendirCache("corrupted")))
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("corrupted"), VendirCacheDataDirName, "file.yaml"), []byte("modified")))
+
+	results, err := g.VerifyCacheEntries(nil)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, r := range results {
+		statuses[r.Name] = r.Status
+	}
+	assert.Equal(t, map[string]string{
+		"corrupted": CacheStatusMismatch,
+		"intact":    CacheStatusOK,
+		"legacy":    CacheStatusNoDigest,
+	}, statuses)
+
+	results, err = g.VerifyCacheEntries([]string{"missing"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusError, results[0].Status)
+}
+
+func TestGlobe_ExportImportCache(t *testing.T) {
+	t.Parallel()
+
+	src := newSharedCacheTestGlobe(t, "", "alpha", "beta", "corrupted")
+	app := src.collectAllApplications()[0]
+	dataDir := filepath.Join(app.expandVendirCache("alpha"), VendirCacheDataDirName)
+	require.NoError(t, os.Symlink("file.yaml", filepath.Join(dataDir, "link.yaml")))
+	// The digests of alpha and beta are recorded on export
+	require.NoError(t, recordCacheDigest(app.expandVendirCache("corrupted")))
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("corrupted"), VendirCacheDataDirName, "file.yaml"), []byte("modified")))
+
+	var archive bytes.Buffer
+	require.NoError(t, src.ExportCache(&archive, nil))
+
+	dst := newSharedCacheTestGlobe(t, "", "beta")
+	results, err := dst.ImportCache(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	imported := map[string]bool{}
+	for _, r := range results {
+		imported[r.Name] = r.Imported
+	}
+	assert.Equal(t, map[string]bool{"alpha": true, "beta": false, "corrupted": false}, imported)
+
+	dstApp := dst.collectAllApplications()[0]
+	assert.Equal(t, CacheStatusOK, verifyCacheEntryDir(dstApp.expandVendirCache("alpha")).Status)
+	target, err := os.Readlink(filepath.Join(dstApp.expandVendirCache("alpha"), VendirCacheDataDirName, "link.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "file.yaml", target)
+	assert.NoDirExists(t, dstApp.expandVendirCache("corrupted"))
+
+	entries, err := os.ReadDir(dst.vendirCacheDir())
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".import-", "staging directory is removed")
+	}
+}
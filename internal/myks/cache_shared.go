@@ -0,0 +1,255 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SharedVendirCacheUser selects the vendir cache in the user cache directory, e.g. $XDG_CACHE_HOME/myks/vendir-cache
+	SharedVendirCacheUser = "user"
+	// Directory in the shared vendir cache with the cache names referenced by every project
+	vendirCacheRefsDirName = ".refs"
+	// Directory in the vendir cache with cross-process lock files
+	vendirCacheLocksDirName = ".locks"
+	// File in a cache entry with the digest of its data directory
+	vendirCacheDigestFileName = "myks-digest"
+)
+
+// vendirCacheRefs lists the cache entries referenced by a project using the shared vendir cache.
+type vendirCacheRefs struct {
+	Project    string   `json:"project"`
+	CacheNames []string `json:"cacheNames"`
+}
+
+// ResolveSharedVendirCacheDir converts the configured shared vendir cache location to an absolute path.
+// An empty value disables the shared cache. The value "user" selects the user cache directory.
+// Environment variables and a leading "~" are expanded.
+func ResolveSharedVendirCacheDir(value string) (string, error) {
+	switch value {
+	case "":
+		return "", nil
+	case SharedVendirCacheUser:
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to find the user cache directory: %w", err)
+		}
+		return filepath.Join(userCacheDir, "myks", "vendir-cache"), nil
+	}
+
+	dir, err := expandUserPath(value)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(dir)
+}
+
+// expandUserPath expands environment variables and a leading "~" in a path.
+func expandUserPath(value string) (string, error) {
+	path := os.ExpandEnv(value)
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to expand %s: %w", value, err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	return path, nil
+}
+
+// vendirCacheDir returns the directory of vendir cache entries: the shared cache if configured,
+// otherwise the cache in the service directory of the project.
+func (cfg *Config) vendirCacheDir() string {
+	if cfg.SharedVendirCacheDir != "" {
+		return cfg.SharedVendirCacheDir
+	}
+	return filepath.Join(cfg.RootDir, cfg.ServiceDirName, cfg.VendirCache)
+}
+
+// writeCacheDigest records the digest of the data of a shared cache entry after it has been synced.
+// The digest is used to detect entries modified or partially written by another project.
+// Entries of the project-local cache are not hashed on every sync, their digest is recorded on export.
+// A digest left from a previous sync is removed, as it no longer matches the data.
+func writeCacheDigest(a *Application, cacheName string) error {
+	cacheDir := a.expandVendirCache(cacheName)
+	if a.cfg.SharedVendirCacheDir == "" {
+		err := os.Remove(filepath.Join(cacheDir, vendirCacheDigestFileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return recordCacheDigest(cacheDir)
+}
+
+// recordCacheDigest hashes the data of the cache entry in the directory and records the digest.
+func recordCacheDigest(entryDir string) error {
+	digest, err := hashDirectory(filepath.Join(entryDir, VendirCacheDataDirName))
+	if err != nil {
+		return fmt.Errorf("hashing cache entry %s: %w", filepath.Base(entryDir), err)
+	}
+	return writeFileAtomic(filepath.Join(entryDir, vendirCacheDigestFileName), []byte(digest+"\n"))
+}
+
+// isCacheDigestValid reports whether the data of a shared cache entry matches the recorded digest.
+// Entries of the project-local cache are always considered valid.
+// Data is only hashed again if any of its files or directories was modified after the digest was recorded.
+func isCacheDigestValid(a *Application, cacheName string) bool {
+	if a.cfg.SharedVendirCacheDir == "" {
+		return true
+	}
+	cacheDir := a.expandVendirCache(cacheName)
+	digestPath := filepath.Join(cacheDir, vendirCacheDigestFileName)
+	dataDir := filepath.Join(cacheDir, VendirCacheDataDirName)
+	digestStat, err := os.Stat(digestPath)
+	if err != nil || digestStat.Size() == 0 {
+		return false
+	}
+	if modified, err := isModifiedAfter(dataDir, digestStat.ModTime()); err == nil && !modified {
+		return true
+	}
+	recorded, err := os.ReadFile(digestPath)
+	if err != nil {
+		return false
+	}
+	digest, err := hashDirectory(dataDir)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(recorded)) == digest
+}
+
+// isModifiedAfter reports whether the directory or any entry in it was modified after the given time.
+// Removed and renamed entries are detected through the modification time of their parent directory.
+// Entries modified at the given time are reported too, as file system timestamps are coarse.
+func isModifiedAfter(dir string, t time.Time) (bool, error) {
+	modified := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(t) {
+			modified = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return modified, err
+}
+
+// refsFilePath returns the path of the file with cache names referenced by the project.
+func (g *Globe) refsFilePath() (string, error) {
+	project, err := filepath.Abs(g.RootDir)
+	if err != nil {
+		return "", err
+	}
+	projectHash, err := hashString(project)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(g.SharedVendirCacheDir, vendirCacheRefsDirName, projectHash+".yaml"), nil
+}
+
+// registerCacheRefs records the cache entries referenced by the project in the shared vendir cache.
+// If replace is false, the names are added to the previously recorded ones.
+func (g *Globe) registerCacheRefs(cacheNames map[string]bool, replace bool) error {
+	if g.SharedVendirCacheDir == "" {
+		return nil
+	}
+	refsPath, err := g.refsFilePath()
+	if err != nil {
+		return err
+	}
+	project, err := filepath.Abs(g.RootDir)
+	if err != nil {
+		return err
+	}
+
+	names := maps.Clone(cacheNames)
+	if !replace {
+		if refs, err := readCacheRefs(refsPath); err != nil {
+			log.Warn().Err(err).Str("file", refsPath).Msg(g.Msg("Unable to read cache references, overwriting"))
+		} else {
+			for _, name := range refs.CacheNames {
+				names[name] = true
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(vendirCacheRefs{Project: project, CacheNames: slices.Sorted(maps.Keys(names))})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(refsPath, data)
+}
+
+func readCacheRefs(path string) (vendirCacheRefs, error) {
+	var refs vendirCacheRefs
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return refs, nil
+	} else if err != nil {
+		return refs, err
+	}
+	return refs, yaml.Unmarshal(data, &refs)
+}
+
+// collectSharedCacheRefs returns the cache names referenced by all projects using the shared vendir cache.
+// References of projects that no longer exist are removed.
+func (g *Globe) collectSharedCacheRefs(dryRun bool) (map[string]bool, error) {
+	refsDir := filepath.Join(g.SharedVendirCacheDir, vendirCacheRefsDirName)
+	entries, err := os.ReadDir(refsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]bool{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %w", err)
+	}
+
+	referenced := map[string]bool{}
+	for _, entry := range entries {
+		refsPath := filepath.Join(refsDir, entry.Name())
+		refs, err := readCacheRefs(refsPath)
+		if err != nil {
+			// Keep everything that might be referenced by a broken file
+			return nil, fmt.Errorf("reading cache references %s: %w", refsPath, err)
+		}
+		if ok, err := isExist(refs.Project); err == nil && !ok {
+			g.removeOrLog(refsPath, dryRun, "cache references of a removed project")
+			continue
+		}
+		for _, name := range refs.CacheNames {
+			referenced[name] = true
+		}
+	}
+	return referenced, nil
+}
+
+// registerCacheRefs records the cache entries linked by the application in the shared vendir cache.
+// It is called before the entries are synced, so a concurrent cleanup in another project keeps them.
+func (v *VendirSyncer) registerCacheRefs(a *Application, linksMap map[string]string) error {
+	if a.cfg.SharedVendirCacheDir == "" || len(linksMap) == 0 {
+		return nil
+	}
+	cacheNames := map[string]bool{}
+	for _, cacheName := range linksMap {
+		cacheNames[cacheName] = true
+	}
+	// The references file of the project is shared by all applications
+	v.cacheRefsMu.Lock()
+	defer v.cacheRefsMu.Unlock()
+	return a.e.g.registerCacheRefs(cacheNames, false)
+}
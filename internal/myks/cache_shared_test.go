@@ -0,0 +1,137 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+func TestResolveSharedVendirCacheDir(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("MYKS_TEST_CACHE", "/srv/cache")
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"user", filepath.Join(cacheHome, "myks", "vendir-cache")},
+		{"$MYKS_TEST_CACHE/myks", "/srv/cache/myks"},
+		{"/abs/cache", "/abs/cache"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveSharedVendirCacheDir(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	got, err := ResolveSharedVendirCacheDir("~/cache")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "cache"), got)
+}
+
+// newSharedCacheTestGlobe creates a project with a single application linked to the given cache entries.
+func newSharedCacheTestGlobe(t *testing.T, sharedDir string, cacheNames ...string) *Globe {
+	t.Helper()
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	g.SharedVendirCacheDir = sharedDir
+	env := &Environment{ID: "env", Dir: "envs/env", cfg: &g.Config, g: g}
+	app := &Application{Name: "app", cfg: &g.Config, e: env}
+	env.Applications = []*Application{app}
+	g.environments = map[string]*Environment{env.Dir: env}
+
+	linksMap := map[string]string{}
+	for _, cacheName := range cacheNames {
+		linksMap[filepath.Join("charts", cacheName)] = cacheName
+		require.NoError(t, writeFile(filepath.Join(app.expandVendirCache(cacheName), VendirCacheDataDirName, "file.yaml"), []byte(cacheName)))
+	}
+	require.NoError(t, NewVendirSyncer(locker.NewLocker()).saveLinksMap(app, linksMap))
+	return g
+}
+
+func TestSharedCacheDigest(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, t.TempDir(), "entry")
+	app := g.collectAllApplications()[0]
+	assert.Equal(t, filepath.Join(g.SharedVendirCacheDir, "entry"), app.expandVendirCache("entry"))
+
+	assert.False(t, isCacheDigestValid(app, "entry"), "entries without a digest are not trusted")
+	require.NoError(t, writeCacheDigest(app, "entry"))
+	assert.True(t, isCacheDigestValid(app, "entry"))
+
+	// Data not modified since the digest was recorded is not hashed again
+	dataFile := filepath.Join(app.expandVendirCache("entry"), VendirCacheDataDirName, "file.yaml")
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.WriteFile(dataFile, []byte("same mtime"), 0o600))
+	require.NoError(t, os.Chtimes(dataFile, past, past))
+	require.NoError(t, os.Chtimes(filepath.Dir(dataFile), past, past))
+	assert.True(t, isCacheDigestValid(app, "entry"))
+
+	require.NoError(t, writeFile(dataFile, []byte("modified")))
+	assert.False(t, isCacheDigestValid(app, "entry"), "modified entries are not trusted")
+
+	require.NoError(t, writeFile(dataFile, []byte("entry")))
+	require.NoError(t, writeCacheDigest(app, "entry"))
+	require.NoError(t, os.Remove(dataFile))
+	assert.False(t, isCacheDigestValid(app, "entry"), "entries with removed files are not trusted")
+
+	// Entries of the project-local cache are not hashed on sync, a previous digest is removed
+	local := newSharedCacheTestGlobe(t, "", "entry")
+	localApp := local.collectAllApplications()[0]
+	digestPath := filepath.Join(localApp.expandVendirCache("entry"), vendirCacheDigestFileName)
+	require.NoError(t, writeFile(digestPath, []byte("stale\n")))
+	require.NoError(t, writeCacheDigest(localApp, "entry"))
+	assert.NoFileExists(t, digestPath)
+	assert.True(t, isCacheDigestValid(localApp, "entry"))
+}
+
+func TestCleanupObsoleteCacheEntries_shared(t *testing.T) {
+	t.Parallel()
+
+	sharedDir := t.TempDir()
+	project1 := newSharedCacheTestGlobe(t, sharedDir, "common", "only-1")
+	project2 := newSharedCacheTestGlobe(t, sharedDir, "common", "only-2")
+	removed := newSharedCacheTestGlobe(t, sharedDir, "only-removed")
+	require.NoError(t, writeFile(filepath.Join(sharedDir, "orphan", VendirCacheDataDirName, "file.yaml"), []byte("orphan")))
+
+	// Projects register their references when syncing
+	for _, g := range []*Globe{project2, removed} {
+		app := g.collectAllApplications()[0]
+		linksMap, err := app.getLinksMap()
+		require.NoError(t, err)
+		require.NoError(t, NewVendirSyncer(nil).registerCacheRefs(app, linksMap))
+	}
+	require.NoError(t, os.RemoveAll(removed.RootDir))
+
+	require.NoError(t, project1.CleanupObsoleteCacheEntries(true))
+	assert.DirExists(t, filepath.Join(sharedDir, "orphan"), "dry run does not remove entries")
+
+	require.NoError(t, project1.CleanupObsoleteCacheEntries(false))
+	assert.DirExists(t, filepath.Join(sharedDir, "common"))
+	assert.DirExists(t, filepath.Join(sharedDir, "only-1"))
+	assert.DirExists(t, filepath.Join(sharedDir, "only-2"), "entries referenced by other projects are kept")
+	assert.NoDirExists(t, filepath.Join(sharedDir, "only-removed"), "references of removed projects are dropped")
+	assert.NoDirExists(t, filepath.Join(sharedDir, "orphan"))
+
+	refs, err := os.ReadDir(filepath.Join(sharedDir, vendirCacheRefsDirName))
+	require.NoError(t, err)
+	assert.Len(t, refs, 2)
+
+	// A reference registered by a sync in another project after the cleanup started is respected
+	require.NoError(t, writeFile(filepath.Join(sharedDir, "late", VendirCacheDataDirName, "file.yaml"), []byte("late")))
+	require.NoError(t, project2.registerCacheRefs(map[string]bool{"late": true}, false))
+	lock := locker.NewFileLocker(filepath.Join(sharedDir, vendirCacheLocksDirName))
+	require.NoError(t, project1.removeUnreferencedCacheEntry(lock, "late"))
+	assert.DirExists(t, filepath.Join(sharedDir, "late"))
+}
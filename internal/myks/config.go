@@ -6,6 +6,8 @@ package myks
 type Config struct {
 	// Global vendir cache dir
 	VendirCache string `default:"vendir-cache"`
+	// Absolute path of the vendir cache shared across projects, replaces VendirCache when set
+	SharedVendirCacheDir string `mapstructure:"-"`
 	// Project root directory
 	RootDir string `default:"."`
 	// Base directory for environments
@@ -49,6 +51,8 @@ type Config struct {
 	VendirLockFileName string `default:"vendir.lock.yaml"`
 	// Name of the file with directory-to-cache-dir mappings
 	VendirLinksMapFileName string `default:"vendir-links.yaml"`
+	// Application lock file name, stored in the application directory of the environment
+	AppLockFileName string `default:"myks.lock.yaml" mapstructure:"app-lock-file-name"`
 	// Prefix for vendir secret environment variables
 	VendirSecretEnvPrefix string `default:"VENDIR_SECRET_"`
 
@@ -23,6 +23,7 @@ type HelmChartOverride struct {
 	BuildDependencies *bool  `yaml:"buildDependencies"`
 	IncludeCRDs       *bool  `yaml:"includeCRDs"`
 	Namespace         string `yaml:"namespace"`
+	Path              string `yaml:"path"`
 	ReleaseName       string `yaml:"releaseName"`
 }
 
@@ -32,6 +33,7 @@ func newHelmConfig(dataValuesYaml string) (HelmConfig, error) {
 		IncludeCRDs       *bool  `yaml:"includeCRDs"`
 		Name              string `yaml:"name"`
 		Namespace         string `yaml:"namespace"`
+		Path              string `yaml:"path"`
 		ReleaseName       string `yaml:"releaseName"`
 	}
 
@@ -76,6 +78,7 @@ func newHelmConfig(dataValuesYaml string) (HelmConfig, error) {
 			BuildDependencies: chart.BuildDependencies,
 			IncludeCRDs:       chart.IncludeCRDs,
 			Namespace:         chart.Namespace,
+			Path:              chart.Path,
 			ReleaseName:       chart.ReleaseName,
 		}
 	}
@@ -66,6 +66,24 @@ helm:
 				Charts:            nil,
 			},
 		},
+		{
+			name: "valid Helm config with chart paths",
+			yamlContent: `
+helm:
+  charts:
+    - name: local
+      path: charts/local
+    - name: archived
+      path: charts/archived-1.0.0.tgz
+`,
+			expectedError: false,
+			expectedCfg: HelmConfig{
+				Charts: map[string]HelmChartOverride{
+					"local":    {Path: "charts/local"},
+					"archived": {Path: "charts/archived-1.0.0.tgz"},
+				},
+			},
+		},
 		{
 			name: "valid Helm config with multiple charts",
 			yamlContent: `
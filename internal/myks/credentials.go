@@ -0,0 +1,461 @@
+package myks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+// Types of vendir credential sources
+const (
+	CredentialSourceEnv           = "env"
+	CredentialSourceDockerConfig  = "docker-config"
+	CredentialSourceNetrc         = "netrc"
+	CredentialSourceEncryptedFile = "encrypted-file"
+	CredentialSourceHelper        = "helper"
+)
+
+// DefaultCredentialKeyEnv is the environment variable holding the key of encrypted credential files.
+const DefaultCredentialKeyEnv = "MYKS_CREDENTIALS_KEY"
+
+const (
+	encryptedCredentialsHeader = "myks-credentials:v1\n"
+	credentialKeyIterations    = 600_000
+	credentialSaltSize         = 16
+)
+
+// ErrCredentialKeyMissing is returned when the key of an encrypted credential file is not set.
+var ErrCredentialKeyMissing = errors.New("credential file key is not set")
+
+// CredentialSource configures where vendir secrets are read from.
+type CredentialSource struct {
+	// One of the CredentialSource* types
+	Type string `mapstructure:"type"`
+	// Path of the docker config, netrc, or encrypted credential file.
+	// Relative paths are resolved against the root directory.
+	Path string `mapstructure:"path"`
+	// Credential helper executable and its arguments
+	Command []string `mapstructure:"command"`
+	// Environment variable holding the key of the encrypted credential file
+	KeyEnv string `mapstructure:"key-env"`
+	// Secret names mapped to registry or machine host names, used by the docker-config, netrc, and helper sources
+	Secrets map[string]string `mapstructure:"secrets"`
+}
+
+// collectVendirSecrets collects vendir secrets from the configured credential sources.
+// Sources are queried in order, the first one providing a secret wins.
+// Without configured sources, secrets are read from environment variables.
+func (v *VendirSyncer) collectVendirSecrets(g *Globe) (map[string]*VendirCredentials, error) {
+	sources := g.CredentialSources
+	if len(sources) == 0 {
+		sources = []CredentialSource{{Type: CredentialSourceEnv}}
+	}
+
+	vendirCredentials := make(map[string]*VendirCredentials)
+	for i := range sources {
+		source := &sources[i]
+		credentials, err := source.collect(g)
+		if err != nil {
+			return nil, fmt.Errorf("reading credentials from %s source: %w", source.Type, err)
+		}
+		for secretName, c := range credentials {
+			if _, ok := vendirCredentials[secretName]; ok {
+				continue
+			}
+			log.Debug().Str("secret", secretName).Str("source", source.Type).Msg(msgWithSteps("sync", v.Ident(), "Found vendir secret"))
+			vendirCredentials[secretName] = c
+		}
+	}
+
+	secretNames := slices.Sorted(maps.Keys(vendirCredentials))
+	log.Debug().Msg(msgWithSteps("sync", v.Ident(), "Found vendir secrets: "+strings.Join(secretNames, ", ")))
+
+	return vendirCredentials, nil
+}
+
+func (s *CredentialSource) collect(g *Globe) (map[string]*VendirCredentials, error) {
+	switch s.Type {
+	case CredentialSourceEnv, "":
+		return collectEnvCredentials(g.VendirSecretEnvPrefix), nil
+	case CredentialSourceDockerConfig:
+		path, err := s.resolvePath(g, defaultDockerConfigPath())
+		if err != nil {
+			return nil, err
+		}
+		return readDockerConfigCredentials(path, s.Secrets)
+	case CredentialSourceNetrc:
+		path, err := s.resolvePath(g, "~/.netrc")
+		if err != nil {
+			return nil, err
+		}
+		return readNetrcCredentials(path, s.Secrets)
+	case CredentialSourceEncryptedFile:
+		path, err := s.resolvePath(g, "")
+		if err != nil {
+			return nil, err
+		}
+		keyEnv := s.KeyEnv
+		if keyEnv == "" {
+			keyEnv = DefaultCredentialKeyEnv
+		}
+		return readEncryptedCredentials(path, os.Getenv(keyEnv))
+	case CredentialSourceHelper:
+		if len(s.Command) == 0 {
+			return nil, errors.New("command is not set")
+		}
+		return collectHelperCredentials(s.Command, s.Secrets), nil
+	default:
+		return nil, fmt.Errorf("unknown credential source type %q", s.Type)
+	}
+}
+
+// resolvePath returns the configured path, or the default one, relative to the root directory.
+func (s *CredentialSource) resolvePath(g *Globe, defaultPath string) (string, error) {
+	path := s.Path
+	if path == "" {
+		path = defaultPath
+	}
+	if path == "" {
+		return "", errors.New("path is not set")
+	}
+	path, err := expandUserPath(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.RootDir, path)
+	}
+	return path, nil
+}
+
+// Suffixes of environment variables holding credential fields
+var envCredentialFields = map[string]func(*VendirCredentials) *string{
+	"_USERNAME":        func(c *VendirCredentials) *string { return &c.Username },
+	"_PASSWORD":        func(c *VendirCredentials) *string { return &c.Password },
+	"_TOKEN":           func(c *VendirCredentials) *string { return &c.Token },
+	"_SSH_PRIVATE_KEY": func(c *VendirCredentials) *string { return &c.SSHPrivateKey },
+	"_SSH_KNOWN_HOSTS": func(c *VendirCredentials) *string { return &c.SSHKnownHosts },
+}
+
+// collectEnvCredentials reads credentials from <prefix><NAME>_<FIELD> environment variables,
+// where FIELD is USERNAME, PASSWORD, TOKEN, SSH_PRIVATE_KEY, or SSH_KNOWN_HOSTS.
+func collectEnvCredentials(prefix string) map[string]*VendirCredentials {
+	vendirCredentials := make(map[string]*VendirCredentials)
+	for _, envPair := range os.Environ() {
+		name, value, _ := strings.Cut(envPair, "=")
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		for suffix, field := range envCredentialFields {
+			if secretName, ok := strings.CutSuffix(rest, suffix); ok && secretName != "" {
+				*field(credentialsFor(vendirCredentials, strings.ToLower(secretName))) = value
+				break
+			}
+		}
+	}
+	dropIncompleteCredentials(vendirCredentials)
+	return vendirCredentials
+}
+
+func credentialsFor(vendirCredentials map[string]*VendirCredentials, secretName string) *VendirCredentials {
+	if vendirCredentials[secretName] == nil {
+		vendirCredentials[secretName] = &VendirCredentials{}
+	}
+	return vendirCredentials[secretName]
+}
+
+func dropIncompleteCredentials(vendirCredentials map[string]*VendirCredentials) {
+	for secretName, credentials := range vendirCredentials {
+		if !credentials.isComplete() {
+			log.Warn().Msg("Incomplete credentials for secret: " + secretName)
+			delete(vendirCredentials, secretName)
+		}
+	}
+}
+
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"` // #nosec G117 -- credentials are passed to vendir, not logged
+		// Bearer token for the registry
+		RegistryToken string `json:"registrytoken"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+func defaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join("~", ".docker", "config.json")
+}
+
+// readDockerConfigCredentials reads credentials of the registries mapped to secret names from a docker config file.
+// Registries configured with a credential helper or store are resolved with the corresponding docker-credential-* executable.
+func readDockerConfigCredentials(path string, secrets map[string]string) (map[string]*VendirCredentials, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is configured by the user
+	if err != nil {
+		return nil, err
+	}
+	var config dockerConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	auths := map[string]*VendirCredentials{}
+	for key, auth := range config.Auths {
+		credentials := &VendirCredentials{Username: auth.Username, Password: auth.Password, Token: auth.RegistryToken}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("decoding auth of %s in %s: %w", key, path, err)
+			}
+			credentials.Username, credentials.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		auths[dockerConfigHost(key)] = credentials
+	}
+
+	vendirCredentials := make(map[string]*VendirCredentials)
+	for secretName, host := range secrets {
+		host = dockerConfigHost(host)
+		helper := config.CredHelpers[host]
+		if helper == "" && auths[host] == nil {
+			helper = config.CredsStore
+		}
+		if helper != "" {
+			credentials, err := runCredentialHelper([]string{"docker-credential-" + helper}, host)
+			if err != nil {
+				log.Warn().Err(err).Str("secret", secretName).Str("registry", host).Msg("Unable to get credentials from docker credential helper")
+				continue
+			}
+			vendirCredentials[secretName] = credentials
+		} else if credentials := auths[host]; credentials != nil {
+			vendirCredentials[secretName] = credentials
+		} else {
+			log.Warn().Str("secret", secretName).Str("registry", host).Msg("No credentials found in docker config")
+		}
+	}
+	dropIncompleteCredentials(vendirCredentials)
+	return vendirCredentials, nil
+}
+
+// dockerConfigHost normalizes a docker config registry key, e.g. "https://index.docker.io/v1/", to a host name.
+func dockerConfigHost(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ := strings.Cut(key, "/")
+	return host
+}
+
+// readNetrcCredentials reads credentials of the machines mapped to secret names from a netrc file.
+func readNetrcCredentials(path string, secrets map[string]string) (map[string]*VendirCredentials, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is configured by the user
+	if err != nil {
+		return nil, err
+	}
+	machines := parseNetrc(string(data))
+
+	vendirCredentials := make(map[string]*VendirCredentials)
+	for secretName, machine := range secrets {
+		credentials, ok := machines[machine]
+		if !ok {
+			// The default entry matches any machine
+			credentials, ok = machines[""]
+		}
+		if !ok {
+			log.Warn().Str("secret", secretName).Str("machine", machine).Msg("No credentials found in netrc file")
+			continue
+		}
+		vendirCredentials[secretName] = &VendirCredentials{Username: credentials.Username, Password: credentials.Password}
+	}
+	dropIncompleteCredentials(vendirCredentials)
+	return vendirCredentials, nil
+}
+
+// parseNetrc returns the credentials of the machines in a netrc file. The default entry is stored under an empty name.
+func parseNetrc(data string) map[string]VendirCredentials {
+	machines := map[string]VendirCredentials{}
+	var machine string
+	var current *VendirCredentials
+	flush := func() {
+		if current != nil {
+			if _, ok := machines[machine]; !ok {
+				machines[machine] = *current
+			}
+		}
+	}
+	fields := strings.Fields(data)
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[i] {
+		case "machine":
+			flush()
+			machine, current = next(), &VendirCredentials{}
+		case "default":
+			flush()
+			machine, current = "", &VendirCredentials{}
+		case "login":
+			if current != nil {
+				current.Username = next()
+			}
+		case "password":
+			if current != nil {
+				current.Password = next()
+			}
+		case "account":
+			next()
+		case "macdef":
+			// Macro definitions are not supported and end the parsing
+			flush()
+			return machines
+		}
+	}
+	flush()
+	return machines
+}
+
+// collectHelperCredentials gets credentials of the hosts mapped to secret names from a credential helper.
+func collectHelperCredentials(command []string, secrets map[string]string) map[string]*VendirCredentials {
+	vendirCredentials := make(map[string]*VendirCredentials)
+	for secretName, serverURL := range secrets {
+		credentials, err := runCredentialHelper(command, serverURL)
+		if err != nil {
+			log.Warn().Err(err).Str("secret", secretName).Msg("Unable to get credentials from credential helper")
+			continue
+		}
+		vendirCredentials[secretName] = credentials
+	}
+	dropIncompleteCredentials(vendirCredentials)
+	return vendirCredentials
+}
+
+// runCredentialHelper gets credentials from a helper implementing the docker credential helper protocol:
+// the helper is called with the "get" argument and the server URL on stdin, and prints the credentials as JSON.
+func runCredentialHelper(command []string, serverURL string) (*VendirCredentials, error) {
+	args := append(slices.Clone(command[1:]), "get")
+	cmd := exec.Command(command[0], args...) // #nosec G204 -- the helper is configured by the user
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %s: %w: %s", command[0], err, strings.TrimSpace(stderr.String()+stdout.String()))
+	}
+	var response struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("parsing output of %s: %w", command[0], err)
+	}
+	return &VendirCredentials{Username: response.Username, Password: response.Secret}, nil
+}
+
+// readEncryptedCredentials reads credentials from a file encrypted with EncryptCredentials.
+// The decrypted content is a YAML map of secret names to credentials: username and password, token,
+// or sshPrivateKey with optional sshKnownHosts.
+func readEncryptedCredentials(path, key string) (map[string]*VendirCredentials, error) {
+	if key == "" {
+		return nil, ErrCredentialKeyMissing
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path is configured by the user
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := DecryptCredentials(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", path, err)
+	}
+	vendirCredentials := make(map[string]*VendirCredentials)
+	if err = yaml.Unmarshal(plaintext, &vendirCredentials); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for secretName, credentials := range vendirCredentials {
+		if credentials == nil {
+			delete(vendirCredentials, secretName)
+		}
+	}
+	dropIncompleteCredentials(vendirCredentials)
+	return vendirCredentials, nil
+}
+
+// EncryptCredentials encrypts a credential file with AES-256-GCM, using a key derived from the passphrase.
+func EncryptCredentials(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, credentialSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newCredentialCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(slices.Concat(salt, nonce), nonce, plaintext, []byte(encryptedCredentialsHeader))
+	return []byte(encryptedCredentialsHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptCredentials decrypts a credential file encrypted with EncryptCredentials.
+func DecryptCredentials(data []byte, passphrase string) ([]byte, error) {
+	encoded, ok := bytes.CutPrefix(data, []byte(encryptedCredentialsHeader))
+	if !ok {
+		return nil, errors.New("not a myks credential file")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < credentialSaltSize {
+		return nil, errors.New("credential file is truncated")
+	}
+	aead, err := newCredentialCipher(passphrase, sealed[:credentialSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[credentialSaltSize:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("credential file is truncated")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(encryptedCredentialsHeader))
+	if err != nil {
+		return nil, errors.New("wrong key or corrupted credential file")
+	}
+	return plaintext, nil
+}
+
+func newCredentialCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, credentialKeyIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
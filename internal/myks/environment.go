@@ -50,6 +50,8 @@ type Environment struct {
 	initialized   bool
 	// Runtime data
 	renderedDataLibFilePath string
+	// Environment data values rendered by initEnvData
+	dataValuesYaml []byte
 	// Found applications
 	foundApplications map[string]string
 }
@@ -151,6 +153,9 @@ func (e *Environment) missingApplications() ([]string, error) {
 	return missingApps, nil
 }
 
+// errEnvironmentIDMissing is returned for environment data files without an environment ID, e.g. of base environments.
+var errEnvironmentIDMissing = errors.New("environment data file missing id")
+
 func (e *Environment) setID() error {
 	yamlBytes, err := os.ReadFile(e.EnvironmentDataFile)
 	if err != nil {
@@ -170,7 +175,7 @@ func (e *Environment) setID() error {
 	}
 
 	if envData.Environment.ID == "" {
-		err = errors.New("environment data file missing id")
+		err = errEnvironmentIDMissing
 		log.Debug().Err(err).Str("file", e.EnvironmentDataFile).Msg("Unable to set environment id")
 		return err
 	}
@@ -202,6 +207,7 @@ func (e *Environment) initEnvData() error {
 		log.Warn().Err(err).Str("dir", e.Dir).Msg(e.Msg("Unable to set environment data"))
 		return fmt.Errorf("parsing environment data yaml: %w", err)
 	}
+	e.dataValuesYaml = envDataYaml
 
 	return nil
 }
@@ -176,6 +176,31 @@ func writeFile(path string, content []byte) error {
 	return os.WriteFile(path, content, 0o600)
 }
 
+// writeFileAtomic writes a file via a temporary file, so concurrent readers never see partial content.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := createDirectory(dir); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.Remove(tmp.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Warn().Err(err).Str("file", tmp.Name()).Msg("Failed to remove temporary file")
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 func isExist(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {
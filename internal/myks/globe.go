@@ -44,9 +44,39 @@ type Globe struct {
 	// Git repository URL
 	GitRepoURL string
 
+	// Resolve vendir sources again and rewrite the application lock files
+	UpdateLock bool
+	// Fail the sync if an application lock file is missing or outdated
+	FrozenLock bool
+	// Sync from the vendir cache only, without network access
+	Offline bool
+	// Sources of vendir secrets, environment variables are used if empty
+	CredentialSources []CredentialSource
+	// Mirrors of vendir sources, applied in order
+	SyncMirrors SourceMirrors
+	// Retries, timeouts, and concurrency limits of vendir syncs
+	SyncSchedule SyncSchedule
+	// User-defined Smart Mode rules, merged with the built-in ones
+	SmartModeRules []SmartModeRule
+
 	// Collected environments for processing
 	environments map[string]*Environment
 
+	// Rendered environments and applications with deleted sources, detected by Smart Mode
+	smartModeDeletions map[string][]string
+	// Reasons of the environments and applications selected by Smart Mode
+	smartModeReasons SmartModeReasons
+	// Input files compared with the change manifest by Smart Mode, recorded after the selection is rendered
+	changeManifestFiles map[string]changeManifestFile
+
+	// Called after each application is processed by Run, concurrently
+	appProcessed func(app *Application, elapsed time.Duration, err error)
+
+	// Plugins or commands run at stages of the pipeline
+	Hooks []Hook
+	// Plugins available to hooks
+	Plugins []Plugin
+
 	// Extra ytt file paths (schema, global lib, config dump).
 	// Populated during New() before any environments are created.
 	extraYttPaths []string
@@ -60,8 +90,19 @@ type YttGlobeData struct {
 
 // VendirCredentials holds registry authentication credentials for vendir sync.
 type VendirCredentials struct {
-	Username string
-	Password string // #nosec G117 -- credentials struct used for vendir auth, not logged
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"` // #nosec G117 -- credentials struct used for vendir auth, not logged
+	// Bearer token, used by image and imgpkg bundle sources
+	Token string `json:"token,omitempty"`
+	// SSH private key and known hosts, used by git sources
+	SSHPrivateKey string `json:"sshPrivateKey,omitempty"`
+	SSHKnownHosts string `json:"sshKnownHosts,omitempty"`
+}
+
+// isComplete reports whether the credentials can be used for authentication:
+// a username with a password, a token, or an SSH private key.
+func (c *VendirCredentials) isComplete() bool {
+	return (c.Username != "" && c.Password != "") || c.Token != "" || c.SSHPrivateKey != ""
 }
 
 // EnvAppMap maps environment directory paths to lists of application names to process.
@@ -174,6 +215,9 @@ func (g *Globe) Run(asyncLevel int, doSync, doRender bool) error {
 	if !doRender && !doSync {
 		return fmt.Errorf("invalid run configuration: both render and sync cannot be false")
 	}
+	if err := g.resolveHooks(); err != nil {
+		return fmt.Errorf("invalid hooks configuration: %w", err)
+	}
 
 	if doRender {
 		for _, env := range g.getInitializedEnvironments() {
@@ -196,7 +240,8 @@ func (g *Globe) Run(asyncLevel int, doSync, doRender bool) error {
 		asyncLevel = -1
 	}
 
-	lock := locker.NewLocker()
+	// Lock files are stored next to the cache entries, so projects sharing the cache coordinate as well
+	lock := locker.NewFileLocker(filepath.Join(g.vendirCacheDir(), vendirCacheLocksDirName))
 
 	var vendirSyncer *VendirSyncer
 	var helmSyncer *HelmSyncer
@@ -204,12 +249,18 @@ func (g *Globe) Run(asyncLevel int, doSync, doRender bool) error {
 	if doSync {
 		// FIXME: It's a workaround due to the fact that only the vendir sync tool currently generates secrets
 		vendirSyncer = NewVendirSyncer(lock)
+		vendirSyncer.UpdateLock = g.UpdateLock
+		vendirSyncer.Frozen = g.FrozenLock
+		vendirSyncer.Offline = g.Offline
+		vendirSyncer.Mirrors = g.SyncMirrors
+		vendirSyncer.Schedule = g.SyncSchedule
 		var err error
 		secrets, err = vendirSyncer.GenerateSecrets(g)
 		if err != nil {
 			return fmt.Errorf("failed to generate secrets for sync tool %s: %w", vendirSyncer.Ident(), err)
 		}
 		helmSyncer = NewHelmSyncer(lock)
+		helmSyncer.Offline = g.Offline
 	}
 
 	var mu sync.Mutex
@@ -222,30 +273,118 @@ func (g *Globe) Run(asyncLevel int, doSync, doRender bool) error {
 		mu.Unlock()
 	}
 
-	eg := errgroup.Group{}
-	eg.SetLimit(asyncLevel)
-	for _, app := range allApps {
-		eg.Go(func() error {
-			appStart := time.Now()
-			if err := g.processApp(app, doSync, doRender, vendirSyncer, helmSyncer, secrets, lock); err != nil {
-				collectErr(err)
+	// Environment hooks run before and after all applications of the environment. Applications of environments with
+	// failed hooks are skipped, failed applications only suppress the post hooks of their environment.
+	hookFailedEnvs := map[*Environment]bool{}
+	appFailedEnvs := map[*Environment]bool{}
+	appErrs := map[*Application]error{}
+	appDurations := map[*Application]time.Duration{}
+	failEnvHook := func(env *Environment, err error) {
+		collectErr(err)
+		mu.Lock()
+		hookFailedEnvs[env] = true
+		mu.Unlock()
+	}
+	appDone := func(app *Application, err error) {
+		mu.Lock()
+		elapsed := appDurations[app]
+		mu.Unlock()
+		pm.TrackAppDuration(elapsed)
+		if g.appProcessed != nil {
+			g.appProcessed(app, elapsed, err)
+		}
+	}
+
+	phases := g.runPhases(doSync, doRender)
+	for i, phase := range phases {
+		lastPhase := i == len(phases)-1
+		preStages, postStages := hookStages(phase.sync, phase.render)
+		_ = process(asyncLevel, slices.Values(g.getInitializedEnvironments()), func(env *Environment) error {
+			mu.Lock()
+			skip := hookFailedEnvs[env]
+			mu.Unlock()
+			if skip {
+				return nil
+			}
+			for _, stage := range preStages {
+				if err := g.runEnvHooks(stage, env); err != nil {
+					failEnvHook(env, err)
+					break
+				}
+			}
+			return nil
+		})
+
+		eg := errgroup.Group{}
+		eg.SetLimit(asyncLevel)
+		for _, app := range allApps {
+			mu.Lock()
+			hookFailed, appFailed := hookFailedEnvs[app.e], appErrs[app] != nil
+			mu.Unlock()
+			if appFailed {
+				continue
+			}
+			if hookFailed {
+				// The error of the hook is already collected
+				err := fmt.Errorf("skipped due to failed hooks of env %s", app.e.ID)
+				mu.Lock()
+				appErrs[app] = err
+				mu.Unlock()
+				if g.appProcessed != nil {
+					g.appProcessed(app, 0, err)
+				}
+				continue
+			}
+			eg.Go(func() error {
+				appStart := time.Now()
+				err := g.processApp(app, phase.sync, phase.render, vendirSyncer, helmSyncer, secrets, lock)
+				mu.Lock()
+				appDurations[app] += time.Since(appStart)
+				if err != nil {
+					appErrs[app] = err
+					appFailedEnvs[app.e] = true
+				}
+				mu.Unlock()
+				if err != nil {
+					collectErr(err)
+				}
+				if err != nil || lastPhase {
+					appDone(app, err)
+				}
+				return nil
+			})
+		}
+		_ = eg.Wait() // eg.Go always returns nil; errors are collected via collectErr
+
+		_ = process(asyncLevel, slices.Values(g.getInitializedEnvironments()), func(env *Environment) error {
+			mu.Lock()
+			skip := hookFailedEnvs[env] || appFailedEnvs[env]
+			mu.Unlock()
+			if skip {
+				return nil
+			}
+			for _, stage := range postStages {
+				if err := g.runEnvHooks(stage, env); err != nil {
+					failEnvHook(env, err)
+					break
+				}
 			}
-			pm.TrackAppDuration(time.Since(appStart))
 			return nil
 		})
 	}
-	_ = eg.Wait() // eg.Go always returns nil; errors are collected via collectErr
 
 	pm.Finish()
 	StorePipelineMetrics(pm)
 	StoreLockerStats(lock.GetStats())
+	if lockErrs := lock.GetStats().FileLockErrors(); len(lockErrs) > 0 {
+		log.Warn().Err(lockErrs[0]).Int("count", len(lockErrs)).Msg(g.Msg("Unable to lock across processes, concurrent myks runs may conflict"))
+	}
 	if vendirSyncer != nil {
 		StoreVendirDedupStats(vendirSyncer.GetDedupStats())
 	}
 	if helmSyncer != nil {
 		StoreHelmDedupStats(helmSyncer.GetDedupStats())
 	}
-
 	for _, env := range g.getInitializedEnvironments() {
 		if err := env.Cleanup(); err != nil {
 			errs = append(errs, fmt.Errorf("cleaning up env %s: %w", env.ID, err))
@@ -255,11 +394,36 @@ func (g *Globe) Run(asyncLevel int, doSync, doRender bool) error {
 	return errors.Join(errs...) //nolint:wrapcheck // each error is already wrapped with its own context
 }
 
+// runPhase selects the steps of a pass over all applications.
+type runPhase struct {
+	sync, render bool
+}
+
+// runPhases splits the run into a sync and a render pass when environment hooks are configured,
+// so that environment sync hooks run around the sync of all applications, and render hooks around their rendering.
+func (g *Globe) runPhases(doSync, doRender bool) []runPhase {
+	if doSync && doRender && slices.ContainsFunc(g.Hooks, func(h Hook) bool { return h.Scope == PluginScopeEnv }) {
+		return []runPhase{{sync: true}, {render: true}}
+	}
+	return []runPhase{{sync: doSync, render: doRender}}
+}
+
 // processApp runs the sync and/or render steps for a single application.
 func (g *Globe) processApp(app *Application, doSync, doRender bool, vendirSyncer *VendirSyncer, helmSyncer *HelmSyncer, secrets string, lock *locker.Locker) error {
 	appID := fmt.Sprintf("%s/%s", app.e.ID, app.Name)
 
+	// Another myks process may be working on the same application
+	unlock, err := app.acquireServiceDirLock(lock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if doSync {
+		if err := g.runAppHooks(HookStagePreSync, app); err != nil {
+			log.Error().Err(err).Str("app", appID).Msg("Hook failed")
+			return err
+		}
 		// TODO: move to Application.Sync or similar, and pass the sync tools there instead of going through
 		// them here
 		if err := vendirSyncer.Sync(app, secrets); err != nil {
@@ -270,9 +434,17 @@ func (g *Globe) processApp(app *Application, doSync, doRender bool, vendirSyncer
 			log.Error().Err(err).Str("app", appID).Msgf("Sync failed for tool %s", helmSyncer.Ident())
 			return err
 		}
+		if err := g.runAppHooks(HookStagePostSync, app); err != nil {
+			log.Error().Err(err).Str("app", appID).Msg("Hook failed")
+			return err
+		}
 	}
 
 	if doRender {
+		if err := g.runAppHooks(HookStagePreRender, app); err != nil {
+			log.Error().Err(err).Str("app", appID).Msg("Hook failed")
+			return err
+		}
 		yamlTemplatingTools := []YamlTemplatingTool{
 			NewHelmRenderer(app, lock),
 			NewYttPkgRenderer(app, lock),
@@ -292,13 +464,26 @@ func (g *Globe) processApp(app *Application, doSync, doRender bool, vendirSyncer
 			log.Error().Err(err).Str("app", appID).Msg("Rendering ArgoCD failed")
 			return err
 		}
+		if err := g.runAppHooks(HookStagePostRender, app); err != nil {
+			log.Error().Err(err).Str("app", appID).Msg("Hook failed")
+			return err
+		}
 	}
 
 	return nil
 }
 
-// ExecPlugin executes a plugin in the context of the globe
+// ExecPlugin executes a plugin in the context of the globe, once per application, environment, or globally,
+// depending on the scope declared in the plugin manifest
 func (g *Globe) ExecPlugin(asyncLevel int, p Plugin, args []string, bufferOutput bool) error {
+	switch p.Manifest().Scope {
+	case PluginScopeGlobal:
+		return p.ExecGlobal(g, args, bufferOutput)
+	case PluginScopeEnv:
+		return process(asyncLevel, slices.Values(g.getInitializedEnvironments()), func(env *Environment) error {
+			return p.ExecEnv(env, args, bufferOutput)
+		})
+	}
 	allApps := g.collectAllApplications()
 	return process(asyncLevel, slices.Values(allApps), func(app *Application) error {
 		return p.Exec(app, args, bufferOutput)
@@ -445,7 +630,21 @@ func (g *Globe) CleanupObsoleteCacheEntries(dryRun bool) error {
 		}
 	}
 
-	cacheDir := filepath.Join(g.RootDir, g.ServiceDirName, g.VendirCache)
+	cacheDir := g.vendirCacheDir()
+	if g.SharedVendirCacheDir != "" {
+		if !dryRun {
+			if err := g.registerCacheRefs(validCacheDirs, true); err != nil {
+				return fmt.Errorf("unable to register cache references: %w", err)
+			}
+		}
+		// Entries referenced by other projects are kept
+		referenced, err := g.collectSharedCacheRefs(dryRun)
+		if err != nil {
+			return err
+		}
+		maps.Copy(validCacheDirs, referenced)
+	}
+
 	cacheEntries, err := os.ReadDir(cacheDir)
 	if os.IsNotExist(err) {
 		log.Debug().Str("dir", cacheDir).Msg("Skipping cleanup of non-existing directory")
@@ -453,8 +652,13 @@ func (g *Globe) CleanupObsoleteCacheEntries(dryRun bool) error {
 	} else if err != nil {
 		return fmt.Errorf("unable to read dir: %w", err)
 	}
+	lock := locker.NewFileLocker(filepath.Join(cacheDir, vendirCacheLocksDirName))
 
 	for _, entry := range cacheEntries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			// Service entries, e.g. references and lock files
+			continue
+		}
 		if !entry.IsDir() {
 			log.Warn().Str("file", cacheDir+"/"+entry.Name()).Msg("Skipping non-directory entry")
 			continue
@@ -464,10 +668,8 @@ func (g *Globe) CleanupObsoleteCacheEntries(dryRun bool) error {
 				log.Info().Str("dir", cacheDir+"/"+entry.Name()).Msg("Would cleanup cache entry")
 				continue
 			}
-			log.Debug().Str("dir", cacheDir+"/"+entry.Name()).Msg("Cleanup cache entry")
-			fullPath := filepath.Join(cacheDir, entry.Name())
-			if err = os.RemoveAll(fullPath); err != nil {
-				log.Warn().Str("dir", fullPath).Msg("Failed to remove directory")
+			if err := g.removeUnreferencedCacheEntry(lock, entry.Name()); err != nil {
+				return err
 			}
 		}
 	}
@@ -475,6 +677,31 @@ func (g *Globe) CleanupObsoleteCacheEntries(dryRun bool) error {
 	return nil
 }
 
+// removeUnreferencedCacheEntry removes a cache entry under its lock, so it is not removed while another myks
+// process is syncing or linking it. Other projects register their references before syncing,
+// so the references of the shared cache are checked again once the lock is held.
+func (g *Globe) removeUnreferencedCacheEntry(lock *locker.Locker, cacheName string) error {
+	unlock := lock.LockNames(slices.Values([]string{cacheName}), true)
+	defer unlock()
+
+	fullPath := filepath.Join(g.vendirCacheDir(), cacheName)
+	if g.SharedVendirCacheDir != "" {
+		referenced, err := g.collectSharedCacheRefs(false)
+		if err != nil {
+			return err
+		}
+		if referenced[cacheName] {
+			log.Debug().Str("dir", fullPath).Msg("Keeping cache entry referenced by another project")
+			return nil
+		}
+	}
+	log.Debug().Str("dir", fullPath).Msg("Cleanup cache entry")
+	if err := os.RemoveAll(fullPath); err != nil {
+		log.Warn().Str("dir", fullPath).Msg("Failed to remove directory")
+	}
+	return nil
+}
+
 // buildYttGlobeData creates the YttGlobeData struct from Globe's git configuration
 func (g *Globe) buildYttGlobeData() *YttGlobeData {
 	return &YttGlobeData{
@@ -0,0 +1,129 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Pipeline stages hooks are bound to
+const (
+	HookStagePreSync    = "pre-sync"
+	HookStagePostSync   = "post-sync"
+	HookStagePreRender  = "pre-render"
+	HookStagePostRender = "post-render"
+)
+
+// Hook runs a plugin or a command at a stage of the pipeline, for every processed application or environment.
+type Hook struct {
+	// Stage of the pipeline: pre-sync, post-sync, pre-render, or post-render
+	Stage string `mapstructure:"stage"`
+	// Name of a plugin, mutually exclusive with Command
+	Plugin string `mapstructure:"plugin"`
+	// Command with its arguments, mutually exclusive with Plugin
+	Command []string `mapstructure:"command"`
+	// Additional arguments
+	Args []string `mapstructure:"args"`
+	// Execution scope: app (default) or env
+	Scope string `mapstructure:"scope"`
+	// Log failures instead of failing the application or environment
+	AllowFailure bool `mapstructure:"allow-failure"`
+
+	// Plugin or command to execute and its arguments, resolved by resolveHooks
+	plugin Plugin
+	args   []string
+}
+
+func (h Hook) name() string {
+	if h.Plugin != "" {
+		return h.Plugin
+	}
+	return strings.Join(h.Command, " ")
+}
+
+// resolveHooks validates the hooks and resolves their plugins.
+func (g *Globe) resolveHooks() error {
+	var errs []error
+	for i := range g.Hooks {
+		h := &g.Hooks[i]
+		if h.Scope == "" {
+			h.Scope = PluginScopeApp
+		}
+		switch {
+		case !slices.Contains([]string{HookStagePreSync, HookStagePostSync, HookStagePreRender, HookStagePostRender}, h.Stage):
+			errs = append(errs, fmt.Errorf("hook %d: unsupported stage %q", i, h.Stage))
+		case h.Scope != PluginScopeApp && h.Scope != PluginScopeEnv:
+			errs = append(errs, fmt.Errorf("hook %d: unsupported scope %q", i, h.Scope))
+		case (h.Plugin == "") == (len(h.Command) == 0):
+			errs = append(errs, fmt.Errorf("hook %d: either plugin or command must be set", i))
+		case h.Plugin != "":
+			idx := slices.IndexFunc(g.Plugins, func(p Plugin) bool { return p.Name() == h.Plugin })
+			if idx < 0 {
+				errs = append(errs, fmt.Errorf("hook %d: plugin %q not found", i, h.Plugin))
+				continue
+			}
+			h.plugin = g.Plugins[idx]
+			h.args = h.Args
+		default:
+			h.plugin = &PluginCmd{name: filepath.Base(h.Command[0]), cmd: h.Command[0]}
+			h.args = append(slices.Clone(h.Command[1:]), h.Args...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hooksFor returns the hooks of a stage and a scope.
+func (g *Globe) hooksFor(stage, scope string) []Hook {
+	var hooks []Hook
+	for _, h := range g.Hooks {
+		if h.Stage == stage && h.Scope == scope {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// runAppHooks runs the application hooks of a stage in order, and stops at the first fatal failure.
+func (g *Globe) runAppHooks(stage string, app *Application) error {
+	for _, h := range g.hooksFor(stage, PluginScopeApp) {
+		if err := h.plugin.Exec(app, h.args, true); err != nil {
+			if h.AllowFailure {
+				log.Warn().Err(err).Msg(app.Msg(stage, "Hook "+h.name()+" failed, continuing"))
+				continue
+			}
+			return fmt.Errorf("%s hook %s: %w", stage, h.name(), err)
+		}
+	}
+	return nil
+}
+
+// runEnvHooks runs the environment hooks of a stage in order, and stops at the first fatal failure.
+func (g *Globe) runEnvHooks(stage string, env *Environment) error {
+	for _, h := range g.hooksFor(stage, PluginScopeEnv) {
+		if err := h.plugin.ExecEnv(env, h.args, true); err != nil {
+			if h.AllowFailure {
+				log.Warn().Err(err).Msg(env.Msg(stage + " hook " + h.name() + " failed, continuing"))
+				continue
+			}
+			return fmt.Errorf("%s hook %s of env %s: %w", stage, h.name(), env.ID, err)
+		}
+	}
+	return nil
+}
+
+// hookStages returns the stages of environment hooks that run before and after processing the applications.
+func hookStages(doSync, doRender bool) (pre, post []string) {
+	if doSync {
+		pre = append(pre, HookStagePreSync)
+		post = append(post, HookStagePostSync)
+	}
+	if doRender {
+		pre = append(pre, HookStagePreRender)
+		post = append(post, HookStagePostRender)
+	}
+	return pre, post
+}
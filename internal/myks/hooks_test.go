@@ -0,0 +1,197 @@
+package myks
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPlugin records its executions and fails if err is set, only for failOn if it is set as well.
+type recordingPlugin struct {
+	name   string
+	err    error
+	failOn string
+	mu     sync.Mutex
+	calls  []string
+}
+
+func (p *recordingPlugin) record(target string, args []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, target+" "+strings.Join(args, ","))
+	if p.failOn != "" && p.failOn != target {
+		return nil
+	}
+	return p.err
+}
+
+func (p *recordingPlugin) Exec(a *Application, args []string, _ bool) error {
+	return p.record(a.e.ID+"/"+a.Name, args)
+}
+
+func (p *recordingPlugin) ExecEnv(e *Environment, args []string, _ bool) error {
+	return p.record(e.ID, args)
+}
+
+func (p *recordingPlugin) ExecGlobal(_ *Globe, args []string, _ bool) error {
+	return p.record("global", args)
+}
+
+func (p *recordingPlugin) Name() string             { return p.name }
+func (p *recordingPlugin) Manifest() PluginManifest { return PluginManifest{Scope: PluginScopeApp} }
+
+func TestGlobe_resolveHooks(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.Plugins = []Plugin{&recordingPlugin{name: "lint"}}
+	g.Hooks = []Hook{
+		{Stage: HookStagePostRender, Plugin: "lint", Args: []string{"--strict"}},
+		{Stage: HookStagePreRender, Command: []string{"./scripts/gen.sh", "data"}, Args: []string{"--force"}, Scope: PluginScopeEnv},
+	}
+	require.NoError(t, g.resolveHooks())
+	require.NoError(t, g.resolveHooks(), "resolving is idempotent")
+	assert.Equal(t, PluginScopeApp, g.Hooks[0].Scope)
+	assert.Equal(t, "lint", g.Hooks[0].plugin.Name())
+	assert.Equal(t, []string{"--strict"}, g.Hooks[0].args)
+	assert.Equal(t, "gen.sh", g.Hooks[1].plugin.Name())
+	assert.Equal(t, []string{"data", "--force"}, g.Hooks[1].args)
+
+	invalid := []Hook{
+		{Stage: "pre-deploy", Plugin: "lint"},
+		{Stage: HookStagePreSync, Plugin: "lint", Scope: PluginScopeGlobal},
+		{Stage: HookStagePreSync},
+		{Stage: HookStagePreSync, Plugin: "lint", Command: []string{"true"}},
+		{Stage: HookStagePreSync, Plugin: "missing"},
+	}
+	for _, hook := range invalid {
+		g.Hooks = []Hook{hook}
+		assert.Error(t, g.resolveHooks(), "%+v", hook)
+	}
+}
+
+func TestGlobe_runHooks(t *testing.T) {
+	t.Parallel()
+
+	failing := &recordingPlugin{name: "failing", err: errors.New("boom")}
+	lint := &recordingPlugin{name: "lint"}
+	g := NewWithDefaults()
+	g.Plugins = []Plugin{failing, lint}
+	env := &Environment{ID: "dev", cfg: &g.Config, g: g}
+	app := &Application{Name: "app", e: env, cfg: &g.Config}
+
+	g.Hooks = []Hook{
+		{Stage: HookStagePreRender, Plugin: "failing", AllowFailure: true},
+		{Stage: HookStagePreRender, Plugin: "lint", Args: []string{"a"}},
+		{Stage: HookStagePostRender, Plugin: "failing"},
+		{Stage: HookStagePostRender, Plugin: "lint"},
+		{Stage: HookStagePreRender, Plugin: "lint", Args: []string{"env"}, Scope: PluginScopeEnv},
+	}
+	require.NoError(t, g.resolveHooks())
+
+	require.NoError(t, g.runAppHooks(HookStagePreRender, app), "allowed failures are not fatal")
+	err := g.runAppHooks(HookStagePostRender, app)
+	require.ErrorContains(t, err, "post-render hook failing: boom")
+	require.NoError(t, g.runEnvHooks(HookStagePreRender, env))
+	require.NoError(t, g.runEnvHooks(HookStagePostSync, env))
+
+	assert.Equal(t, []string{"dev/app ", "dev/app "}, failing.calls)
+	assert.Equal(t, []string{"dev/app a", "dev env"}, lint.calls, "hooks after a fatal failure are skipped")
+}
+
+func Test_hookStages(t *testing.T) {
+	t.Parallel()
+
+	pre, post := hookStages(true, true)
+	assert.Equal(t, []string{HookStagePreSync, HookStagePreRender}, pre)
+	assert.Equal(t, []string{HookStagePostSync, HookStagePostRender}, post)
+	pre, post = hookStages(false, true)
+	assert.Equal(t, []string{HookStagePreRender}, pre)
+	assert.Equal(t, []string{HookStagePostRender}, post)
+}
+
+func Test_runPhases(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.Hooks = []Hook{{Stage: HookStagePostRender, Scope: PluginScopeApp}}
+	assert.Equal(t, []runPhase{{sync: true, render: true}}, g.runPhases(true, true))
+	g.Hooks = append(g.Hooks, Hook{Stage: HookStagePostSync, Scope: PluginScopeEnv})
+	assert.Equal(t, []runPhase{{sync: true}, {render: true}}, g.runPhases(true, true), "env hooks split sync and render")
+	assert.Equal(t, []runPhase{{render: true}}, g.runPhases(false, true))
+}
+
+// newHooksTestGlobe returns a globe with initialized environments of applications that are not rendered yet.
+func newHooksTestGlobe(t *testing.T, envApps map[string][]string) *Globe {
+	t.Helper()
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{}
+	for envID, appNames := range envApps {
+		env := &Environment{ID: envID, Dir: "envs/" + envID, cfg: &g.Config, g: g, initialized: true}
+		for _, name := range appNames {
+			env.Applications = append(env.Applications, &Application{Name: name, Prototype: "prototypes/" + name, e: env, cfg: &g.Config})
+		}
+		g.environments[env.Dir] = env
+	}
+	return g
+}
+
+// recordProcessedApps records the results of processed applications by their IDs.
+func recordProcessedApps(g *Globe) map[string]error {
+	var mu sync.Mutex
+	processed := map[string]error{}
+	g.appProcessed = func(app *Application, _ time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed[app.e.ID+"/"+app.Name] = err
+	}
+	return processed
+}
+
+func TestGlobe_Run_failedAppDoesNotSkipOthers(t *testing.T) {
+	t.Parallel()
+
+	failing := &recordingPlugin{name: "failing", err: errors.New("boom"), failOn: "dev/app1"}
+	envHook := &recordingPlugin{name: "env-hook"}
+	g := newHooksTestGlobe(t, map[string][]string{"dev": {"app1", "app2", "app3"}})
+	g.Plugins = []Plugin{failing, envHook}
+	g.Hooks = []Hook{
+		{Stage: HookStagePreRender, Plugin: "failing"},
+		{Stage: HookStagePostRender, Plugin: "env-hook", Scope: PluginScopeEnv},
+	}
+	processed := recordProcessedApps(g)
+
+	err := g.Run(1, false, true)
+	require.ErrorContains(t, err, "pre-render hook failing: boom")
+	assert.ElementsMatch(t, []string{"dev/app1 ", "dev/app2 ", "dev/app3 "}, failing.calls, "all applications are processed")
+	assert.Len(t, processed, 3)
+	assert.ErrorContains(t, processed["dev/app1"], "boom")
+	assert.Empty(t, envHook.calls, "post hooks are skipped for environments with failed applications")
+}
+
+func TestGlobe_Run_failedEnvHookSkipsApps(t *testing.T) {
+	t.Parallel()
+
+	envHook := &recordingPlugin{name: "env-hook", err: errors.New("boom"), failOn: "dev"}
+	appHook := &recordingPlugin{name: "app-hook"}
+	g := newHooksTestGlobe(t, map[string][]string{"dev": {"app1", "app2"}, "prod": {"app1"}})
+	g.Plugins = []Plugin{envHook, appHook}
+	g.Hooks = []Hook{
+		{Stage: HookStagePreRender, Plugin: "env-hook", Scope: PluginScopeEnv},
+		{Stage: HookStagePreRender, Plugin: "app-hook"},
+	}
+	processed := recordProcessedApps(g)
+
+	err := g.Run(1, false, true)
+	require.ErrorContains(t, err, "pre-render hook env-hook of env dev: boom")
+	assert.Equal(t, []string{"prod/app1 "}, appHook.calls, "applications of environments with failed hooks are skipped")
+	assert.Len(t, processed, 3, "skipped applications are reported")
+	assert.ErrorContains(t, processed["dev/app1"], "skipped due to failed hooks of env dev")
+	assert.ErrorContains(t, processed["dev/app2"], "skipped due to failed hooks of env dev")
+}
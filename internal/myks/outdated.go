@@ -0,0 +1,462 @@
+package myks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	gv "github.com/hashicorp/go-version"
+	"github.com/rs/zerolog/log"
+	yaml "gopkg.in/yaml.v3"
+	sigyaml "sigs.k8s.io/yaml"
+)
+
+const outdatedStepName = "outdated"
+
+// Source types reported by Outdated
+const (
+	SourceTypeHelmChart    = "helmChart"
+	SourceTypeGit          = "git"
+	SourceTypeImage        = "image"
+	SourceTypeImgpkgBundle = "imgpkgBundle"
+)
+
+// Update classifications reported by Outdated
+const (
+	UpdateNone       = "up-to-date"
+	UpdateMajor      = "major"
+	UpdateMinor      = "minor"
+	UpdatePatch      = "patch"
+	UpdateRelease    = "release"
+	UpdatePrerelease = "prerelease"
+	UpdateUnknown    = "unknown"
+)
+
+// OutdatedSource describes the current and the latest available version of a vendir source.
+type OutdatedSource struct {
+	Env  string `json:"env"`
+	App  string `json:"app"`
+	Path string `json:"path"`
+	// Name of the source in the vendir config, the same as used by `myks update --source`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Location of the source: helm repository, git URL, or OCI repository
+	Location string `json:"location"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest,omitempty"`
+	Update   string `json:"update"`
+	Error    string `json:"error,omitempty"`
+
+	// Name of the vendir secret used to access the source
+	secretName string
+}
+
+// VersionResolver lists available versions of vendir sources.
+// Results are cached, so every repository is queried once per run.
+type VersionResolver struct {
+	HTTPClient *http.Client
+	// ListGitTags lists the tags of a git repository
+	ListGitTags func(url string) ([]string, error)
+	// ListOCITags lists the tags of an OCI repository, using the default keychain if auth is nil
+	ListOCITags func(repository string, auth authn.Authenticator) ([]string, error)
+	// Vendir secrets by name, used for sources that reference them
+	Credentials map[string]*VendirCredentials
+
+	cache sync.Map
+}
+
+type versionsResult struct {
+	once     sync.Once
+	versions []string
+	err      error
+}
+
+// NewVersionResolver creates a VersionResolver that queries remote repositories.
+func NewVersionResolver() *VersionResolver {
+	return &VersionResolver{
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		ListGitTags: listGitTags,
+		ListOCITags: listOCITags,
+	}
+}
+
+// LoadCredentials reads the vendir secrets from the credential sources, the same way sync does.
+func (r *VersionResolver) LoadCredentials(g *Globe) error {
+	credentials, err := NewVendirSyncer(nil).collectVendirSecrets(g)
+	if err != nil {
+		return err
+	}
+	r.Credentials = credentials
+	return nil
+}
+
+func (r *VersionResolver) cached(key string, list func() ([]string, error)) ([]string, error) {
+	value, _ := r.cache.LoadOrStore(key, &versionsResult{})
+	result := value.(*versionsResult)
+	result.once.Do(func() {
+		result.versions, result.err = list()
+	})
+	return result.versions, result.err
+}
+
+// Outdated reports the current and the latest versions of the vendir sources of all initialized applications.
+func (g *Globe) Outdated(asyncLevel int, resolver *VersionResolver) ([]OutdatedSource, error) {
+	var mu sync.Mutex
+	var result []OutdatedSource
+	err := process(asyncLevel, slices.Values(g.collectAllApplications()), func(app *Application) error {
+		sources, err := app.outdatedSources(resolver)
+		if err != nil {
+			return fmt.Errorf("checking sources of %s/%s: %w", app.e.ID, app.Name, err)
+		}
+		mu.Lock()
+		result = append(result, sources...)
+		mu.Unlock()
+		return nil
+	})
+	slices.SortFunc(result, func(x, y OutdatedSource) int {
+		return strings.Compare(x.Env+"\x00"+x.App+"\x00"+x.Path, y.Env+"\x00"+y.App+"\x00"+y.Path)
+	})
+	return result, err
+}
+
+func (a *Application) outdatedSources(resolver *VersionResolver) ([]OutdatedSource, error) {
+	vendirConfig, err := a.readRenderedVendirConfig()
+	if errors.Is(err, ErrNoVendirConfig) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var result []OutdatedSource
+	for _, dir := range vendirConfig.Directories {
+		for _, content := range dir.Contents {
+			source, ok := resolver.checkContent(content)
+			if !ok {
+				continue
+			}
+			source.Env = a.e.ID
+			source.App = a.Name
+			source.Path = filepath.ToSlash(filepath.Join(dir.Path, content.Path))
+			if source.Error != "" {
+				log.Warn().Str("path", source.Path).Str("error", source.Error).Msg(a.Msg(outdatedStepName, "Unable to list versions"))
+			}
+			result = append(result, source)
+		}
+	}
+	return result, nil
+}
+
+// readRenderedVendirConfig renders the vendir config of the application and returns it parsed.
+func (a *Application) readRenderedVendirConfig() (vendirconf.Config, error) {
+	v := NewVendirSyncer(nil)
+	if err := v.renderVendirConfig(a); err != nil {
+		return vendirconf.Config{}, err
+	}
+	data, err := os.ReadFile(a.expandServicePath(a.cfg.VendirConfigFileName))
+	if err != nil {
+		return vendirconf.Config{}, err
+	}
+	var vendirConfig vendirconf.Config
+	if err = sigyaml.Unmarshal(data, &vendirConfig); err != nil {
+		return vendirconf.Config{}, fmt.Errorf("failed to parse vendir config: %w", err)
+	}
+	return vendirConfig, nil
+}
+
+// checkContent lists the available versions of a vendir content and finds the latest one.
+// Returns false for content types that have no versions, e.g. directory or inline.
+func (r *VersionResolver) checkContent(content vendirconf.DirectoryContents) (OutdatedSource, bool) { //nolint:gocritic // external type
+	source, ok := describeContent(content)
+	if !ok {
+		return OutdatedSource{}, false
+	}
+	versions, err := r.listVersions(source)
+	if err != nil {
+		source.Update = UpdateUnknown
+		source.Error = err.Error()
+		return source, true
+	}
+	source.Latest, source.Update = latestVersion(source.Current, versions)
+	return source, true
+}
+
+// describeContent returns the type, name, location, and current version of a vendir content.
+// Returns false for content types that have no versions, e.g. directory or inline.
+func describeContent(content vendirconf.DirectoryContents) (OutdatedSource, bool) { //nolint:gocritic // external type
+	switch {
+	case content.HelmChart != nil:
+		chart := content.HelmChart
+		source := OutdatedSource{Type: SourceTypeHelmChart, Name: chart.Name, Current: chart.Version}
+		if chart.Repository != nil {
+			source.Location = chart.Repository.URL
+			source.secretName = localRefName(chart.Repository.SecretRef)
+		}
+		return source, true
+	case content.Git != nil:
+		return OutdatedSource{Type: SourceTypeGit, Name: content.Git.URL, Location: content.Git.URL, Current: content.Git.Ref}, true
+	case content.Image != nil:
+		repo, tag := splitImageReference(content.Image.URL)
+		return OutdatedSource{
+			Type: SourceTypeImage, Name: repo, Location: repo, Current: tag,
+			secretName: localRefName(content.Image.SecretRef),
+		}, true
+	case content.ImgpkgBundle != nil:
+		repo, tag := splitImageReference(content.ImgpkgBundle.Image)
+		return OutdatedSource{
+			Type: SourceTypeImgpkgBundle, Name: repo, Location: repo, Current: tag,
+			secretName: localRefName(content.ImgpkgBundle.SecretRef),
+		}, true
+	default:
+		return OutdatedSource{}, false
+	}
+}
+
+func localRefName(ref *vendirconf.DirectoryContentsLocalRef) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.Name
+}
+
+// listVersions lists the available versions of a source.
+func (r *VersionResolver) listVersions(source OutdatedSource) ([]string, error) { //nolint:gocritic // passed by value for simplicity
+	switch source.Type {
+	case SourceTypeHelmChart:
+		return r.helmChartVersions(source.Location, source.Name, source.secretName)
+	case SourceTypeGit:
+		return r.cached("git\x00"+source.Location, func() ([]string, error) {
+			return r.ListGitTags(source.Location)
+		})
+	case SourceTypeImage, SourceTypeImgpkgBundle:
+		return r.ociTags(source.Location, source.secretName, false)
+	default:
+		return nil, fmt.Errorf("unsupported source type %s", source.Type)
+	}
+}
+
+func (r *VersionResolver) helmChartVersions(repoURL, chartName, secretName string) ([]string, error) {
+	if repoURL == "" {
+		return nil, errors.New("helm chart repository is not set")
+	}
+	if after, ok := strings.CutPrefix(repoURL, "oci://"); ok {
+		return r.ociTags(strings.TrimSuffix(after, "/")+"/"+chartName, secretName, true)
+	}
+	return r.cached("helm\x00"+repoURL+"\x00"+chartName+"\x00"+secretName, func() ([]string, error) {
+		entries, err := r.helmRepoIndex(repoURL, secretName)
+		if err != nil {
+			return nil, err
+		}
+		versions, ok := entries[chartName]
+		if !ok {
+			return nil, fmt.Errorf("chart %s not found in repository %s", chartName, repoURL)
+		}
+		return versions, nil
+	})
+}
+
+// helmRepoIndex downloads the index of a helm repository and returns the chart versions by chart name.
+func (r *VersionResolver) helmRepoIndex(repoURL, secretName string) (map[string][]string, error) {
+	value, _ := r.cache.LoadOrStore("helm-index\x00"+strings.TrimSuffix(repoURL, "/")+"\x00"+secretName, &helmIndexResult{})
+	result := value.(*helmIndexResult)
+	result.once.Do(func() {
+		result.entries, result.err = r.fetchHelmRepoIndex(repoURL, secretName)
+	})
+	return result.entries, result.err
+}
+
+type helmIndexResult struct {
+	once    sync.Once
+	entries map[string][]string
+	err     error
+}
+
+func (r *VersionResolver) fetchHelmRepoIndex(repoURL, secretName string) (map[string][]string, error) {
+	indexURL := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, indexURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if credentials, err := r.credentials(secretName); err != nil {
+		return nil, err
+	} else if credentials != nil {
+		if credentials = credentials.forHelm(); credentials != nil {
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+		}
+	}
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", indexURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}
+	if err = yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", indexURL, err)
+	}
+	entries := make(map[string][]string, len(index.Entries))
+	for chartName, charts := range index.Entries {
+		for _, chart := range charts {
+			entries[chartName] = append(entries[chartName], chart.Version)
+		}
+	}
+	return entries, nil
+}
+
+// ociTags lists the tags of an OCI repository. Helm chart sources only support basic auth, like in sync.
+func (r *VersionResolver) ociTags(repository, secretName string, helm bool) ([]string, error) {
+	return r.cached("oci\x00"+repository+"\x00"+secretName, func() ([]string, error) {
+		credentials, err := r.credentials(secretName)
+		if err != nil {
+			return nil, err
+		}
+		var auth authn.Authenticator
+		switch {
+		case credentials == nil:
+		case helm:
+			if credentials = credentials.forHelm(); credentials != nil {
+				auth = &authn.Basic{Username: credentials.Username, Password: credentials.Password}
+			}
+		case credentials.Token != "":
+			auth = &authn.Bearer{Token: credentials.Token}
+		default:
+			auth = &authn.Basic{Username: credentials.Username, Password: credentials.Password}
+		}
+		return r.ListOCITags(repository, auth)
+	})
+}
+
+// credentials returns the vendir secret of the given name, or nil if the source references no secret.
+func (r *VersionResolver) credentials(secretName string) (*VendirCredentials, error) {
+	if secretName == "" {
+		return nil, nil
+	}
+	credentials, ok := r.Credentials[secretName]
+	if !ok {
+		return nil, fmt.Errorf("vendir secret %s is not found", secretName)
+	}
+	return credentials, nil
+}
+
+func listOCITags(repository string, auth authn.Authenticator) ([]string, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return remote.List(repo, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	}
+	return remote.List(repo, remote.WithAuth(auth))
+}
+
+func listGitTags(url string) ([]string, error) {
+	cmd := exec.Command("git", "ls-remote", "--tags", "--refs", url) // #nosec G204 -- the URL comes from the vendir config
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git ls-remote %s: %s", url, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseGitLsRemoteTags(string(out)), nil
+}
+
+// parseGitLsRemoteTags extracts tag names from the output of `git ls-remote --tags --refs`.
+func parseGitLsRemoteTags(out string) []string {
+	var tags []string
+	for line := range strings.SplitSeq(out, "\n") {
+		_, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// splitImageReference splits an image reference into the repository and the tag or digest.
+func splitImageReference(ref string) (string, string) {
+	if repo, digest, ok := strings.Cut(ref, "@"); ok {
+		return repo, digest
+	}
+	lastSlash := strings.LastIndex(ref, "/")
+	if i := strings.LastIndex(ref, ":"); i > lastSlash {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+// latestVersion finds the highest semantic version among the available versions
+// and classifies the update from the current version.
+// Pre-releases are only considered if the current version is a pre-release itself.
+// Versions keep their original form, e.g. with a "v" prefix.
+func latestVersion(current string, available []string) (string, string) {
+	currentVersion, currentErr := gv.NewSemver(current)
+	allowPrerelease := currentErr == nil && currentVersion.Prerelease() != ""
+
+	var latest *gv.Version
+	latestRaw := ""
+	for _, raw := range available {
+		v, err := gv.NewSemver(raw)
+		if err != nil || (v.Prerelease() != "" && !allowPrerelease) {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, latestRaw = v, raw
+		}
+	}
+
+	switch {
+	case latest == nil:
+		return "", UpdateUnknown
+	case currentErr != nil:
+		return latestRaw, UpdateUnknown
+	case !latest.GreaterThan(currentVersion):
+		return latestRaw, UpdateNone
+	}
+
+	cur, lat := currentVersion.Segments(), latest.Segments()
+	switch {
+	case lat[0] != cur[0]:
+		return latestRaw, UpdateMajor
+	case lat[1] != cur[1]:
+		return latestRaw, UpdateMinor
+	case lat[2] != cur[2]:
+		return latestRaw, UpdatePatch
+	case latest.Prerelease() == "":
+		// The final release of the current pre-release, e.g. 1.2.0-rc.1 to 1.2.0
+		return latestRaw, UpdateRelease
+	default:
+		return latestRaw, UpdatePrerelease
+	}
+}
@@ -0,0 +1,241 @@
+package myks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		current    string
+		available  []string
+		wantLatest string
+		wantUpdate string
+	}{
+		{"major", "1.2.3", []string{"1.2.3", "1.3.0", "2.0.0"}, "2.0.0", UpdateMajor},
+		{"minor", "1.2.3", []string{"1.2.3", "1.2.4", "1.3.0"}, "1.3.0", UpdateMinor},
+		{"patch", "1.2.3", []string{"1.2.3", "1.2.4"}, "1.2.4", UpdatePatch},
+		{"up to date", "1.2.3", []string{"1.0.0", "1.2.3"}, "1.2.3", UpdateNone},
+		{"v prefix is kept", "v1.2.3", []string{"v1.2.3", "v1.2.10", "latest"}, "v1.2.10", UpdatePatch},
+		{"prereleases are skipped", "1.2.3", []string{"1.2.3", "2.0.0-rc.1"}, "1.2.3", UpdateNone},
+		{"prereleases are considered for prereleases", "2.0.0-rc.1", []string{"1.2.3", "2.0.0-rc.2"}, "2.0.0-rc.2", UpdatePrerelease},
+		{"prerelease to release", "2.0.0-rc.1", []string{"2.0.0-rc.2", "2.0.0"}, "2.0.0", UpdateRelease},
+		{"prerelease to release of a minor version", "1.2.0-rc.1", []string{"1.2.0"}, "1.2.0", UpdateRelease},
+		{"prerelease to release with v prefix", "v1.2.0-beta.3", []string{"v1.2.0-beta.4", "v1.2.0"}, "v1.2.0", UpdateRelease},
+		{"prerelease to next patch", "2.0.0-rc.1", []string{"2.0.1"}, "2.0.1", UpdatePatch},
+		{"non-semver current version", "main", []string{"v1.0.0"}, "v1.0.0", UpdateUnknown},
+		{"no semver versions available", "1.0.0", []string{"latest", "main"}, "", UpdateUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			latest, update := latestVersion(tt.current, tt.available)
+			assert.Equal(t, tt.wantLatest, latest)
+			assert.Equal(t, tt.wantUpdate, update)
+		})
+	}
+}
+
+func TestParseGitLsRemoteTags(t *testing.T) {
+	t.Parallel()
+
+	out := "0123\trefs/tags/v1.0.0\nabcd\trefs/tags/v1.1.0\n\nbad line\n"
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, parseGitLsRemoteTags(out))
+}
+
+func TestSplitImageReference(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref      string
+		wantRepo string
+		wantTag  string
+	}{
+		{"ghcr.io/org/config:1.0.0", "ghcr.io/org/config", "1.0.0"},
+		{"localhost:5000/config:1.0.0", "localhost:5000/config", "1.0.0"},
+		{"localhost:5000/config", "localhost:5000/config", "latest"},
+		{"ghcr.io/org/config@sha256:abc", "ghcr.io/org/config", "sha256:abc"},
+	}
+	for _, tt := range tests {
+		repo, tag := splitImageReference(tt.ref)
+		assert.Equal(t, tt.wantRepo, repo, tt.ref)
+		assert.Equal(t, tt.wantTag, tag, tt.ref)
+	}
+}
+
+func TestVersionResolver_checkContent(t *testing.T) {
+	t.Parallel()
+
+	var indexRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/charts/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		indexRequests.Add(1)
+		_, _ = w.Write([]byte(`apiVersion: v1
+entries:
+  nginx:
+    - version: 1.3.0
+    - version: 1.2.3
+    - version: 2.0.0-beta.1
+  redis:
+    - version: 18.0.0
+`))
+	}))
+	defer server.Close()
+
+	resolver := NewVersionResolver()
+	resolver.HTTPClient = server.Client()
+	resolver.ListGitTags = func(string) ([]string, error) { return []string{"v0.1.0", "v0.2.0"}, nil }
+	resolver.ListOCITags = func(repository string, _ authn.Authenticator) ([]string, error) {
+		if repository == "ghcr.io/org/charts/app" {
+			return []string{"3.0.0", "3.0.1"}, nil
+		}
+		return nil, errors.New("unauthorized")
+	}
+
+	helmChart := func(name, version, repo string) vendirconf.DirectoryContents {
+		return vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{
+			Name: name, Version: version, Repository: &vendirconf.DirectoryContentsHelmChartRepo{URL: repo},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		content vendirconf.DirectoryContents
+		want    OutdatedSource
+		wantOk  bool
+	}{
+		{
+			name:    "helm repository",
+			content: helmChart("nginx", "1.2.3", server.URL+"/charts/"),
+			want: OutdatedSource{
+				Type: SourceTypeHelmChart, Name: "nginx", Location: server.URL + "/charts/",
+				Current: "1.2.3", Latest: "1.3.0", Update: UpdateMinor,
+			},
+			wantOk: true,
+		},
+		{
+			name:    "helm oci repository",
+			content: helmChart("app", "3.0.0", "oci://ghcr.io/org/charts"),
+			want: OutdatedSource{
+				Type: SourceTypeHelmChart, Name: "app", Location: "oci://ghcr.io/org/charts",
+				Current: "3.0.0", Latest: "3.0.1", Update: UpdatePatch,
+			},
+			wantOk: true,
+		},
+		{
+			name:    "chart missing in index",
+			content: helmChart("postgres", "1.0.0", server.URL+"/charts"),
+			want: OutdatedSource{
+				Type: SourceTypeHelmChart, Name: "postgres", Location: server.URL + "/charts",
+				Current: "1.0.0", Update: UpdateUnknown,
+				Error: "chart postgres not found in repository " + server.URL + "/charts",
+			},
+			wantOk: true,
+		},
+		{
+			name: "git",
+			content: vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{
+				URL: "https://github.com/org/repo", Ref: "v0.1.0",
+			}},
+			want: OutdatedSource{
+				Type: SourceTypeGit, Name: "https://github.com/org/repo", Location: "https://github.com/org/repo",
+				Current: "v0.1.0", Latest: "v0.2.0", Update: UpdateMinor,
+			},
+			wantOk: true,
+		},
+		{
+			name: "image with registry error",
+			content: vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{
+				URL: "ghcr.io/org/config:1.0.0",
+			}},
+			want: OutdatedSource{
+				Type: SourceTypeImage, Name: "ghcr.io/org/config", Location: "ghcr.io/org/config",
+				Current: "1.0.0", Update: UpdateUnknown, Error: "unauthorized",
+			},
+			wantOk: true,
+		},
+		{
+			name: "directory is skipped",
+			content: vendirconf.DirectoryContents{Directory: &vendirconf.DirectoryContentsDirectory{
+				Path: "local",
+			}},
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		source, ok := resolver.checkContent(tt.content)
+		assert.Equal(t, tt.wantOk, ok, tt.name)
+		assert.Equal(t, tt.want, source, tt.name)
+	}
+
+	_, ok := resolver.checkContent(helmChart("redis", "17.0.0", server.URL+"/charts/"))
+	require.True(t, ok)
+	assert.Equal(t, int32(1), indexRequests.Load(), "the repository index is downloaded once")
+}
+
+func TestVersionResolver_credentials(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "token" || password != "secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("entries:\n  nginx:\n    - version: 1.3.0\n"))
+	}))
+	defer server.Close()
+
+	var ociAuth sync.Map
+	resolver := NewVersionResolver()
+	resolver.HTTPClient = server.Client()
+	resolver.ListOCITags = func(repository string, auth authn.Authenticator) ([]string, error) {
+		ociAuth.Store(repository, auth)
+		return []string{"1.0.0"}, nil
+	}
+	resolver.Credentials = map[string]*VendirCredentials{
+		"private": {Token: "secret-token"},
+	}
+	secretRef := &vendirconf.DirectoryContentsLocalRef{Name: "private"}
+	helmChart := func(name, repo string, secretRef *vendirconf.DirectoryContentsLocalRef) vendirconf.DirectoryContents {
+		return vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{
+			Name: name, Version: "1.0.0", Repository: &vendirconf.DirectoryContentsHelmChartRepo{URL: repo, SecretRef: secretRef},
+		}}
+	}
+
+	source, _ := resolver.checkContent(helmChart("nginx", server.URL, secretRef))
+	assert.Empty(t, source.Error)
+	assert.Equal(t, "1.3.0", source.Latest, "the token is passed as the password, like in sync")
+
+	source, _ = resolver.checkContent(helmChart("nginx", server.URL+"/public", nil))
+	assert.Contains(t, source.Error, "401")
+
+	source, _ = resolver.checkContent(helmChart("nginx", server.URL+"/missing", &vendirconf.DirectoryContentsLocalRef{Name: "missing"}))
+	assert.Equal(t, "vendir secret missing is not found", source.Error)
+
+	resolver.checkContent(helmChart("app", "oci://ghcr.io/org/charts", secretRef))
+	resolver.checkContent(vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "ghcr.io/org/config:1.0.0", SecretRef: secretRef}})
+	resolver.checkContent(vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "ghcr.io/org/public:1.0.0"}})
+
+	auth, _ := ociAuth.Load("ghcr.io/org/charts/app")
+	assert.Equal(t, &authn.Basic{Username: "token", Password: "secret-token"}, auth)
+	auth, _ = ociAuth.Load("ghcr.io/org/config")
+	assert.Equal(t, &authn.Bearer{Token: "secret-token"}, auth)
+	auth, ok := ociAuth.Load("ghcr.io/org/public")
+	require.True(t, ok)
+	assert.Nil(t, auth, "the default keychain is used without a secret")
+}
@@ -0,0 +1,114 @@
+package myks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+
+	"github.com/mykso/myks/pkg/plugin"
+)
+
+// pluginDataValuesEnvLimit is the size of data values above which MYKS_DATA_VALUES is not set.
+// Linux limits a single "NAME=value" environment string, including the terminating null byte, to 128KiB.
+const pluginDataValuesEnvLimit = 128*1024 - len(plugin.EnvVarDataValues) - len("=\x00")
+
+// pluginContext is a temporary directory with the data of a single plugin invocation.
+type pluginContext struct {
+	dir string
+}
+
+func newPluginContext() (*pluginContext, error) {
+	dir, err := os.MkdirTemp("", "myks-plugin-")
+	if err != nil {
+		return nil, fmt.Errorf("creating plugin context directory: %w", err)
+	}
+	return &pluginContext{dir: dir}, nil
+}
+
+func (c *pluginContext) remove() {
+	if err := os.RemoveAll(c.dir); err != nil {
+		log.Warn().Err(err).Str("dir", c.dir).Msg("Unable to remove plugin context directory")
+	}
+}
+
+// writeDataValues writes the data values as YAML and JSON.
+func (c *pluginContext) writeDataValues(dataValues []byte) error {
+	dataJSON, err := yaml.YAMLToJSON(dataValues)
+	if err != nil {
+		return fmt.Errorf("converting data values to JSON: %w", err)
+	}
+	if err := writeFile(filepath.Join(c.dir, plugin.ContextFileDataValuesYAML), dataValues); err != nil {
+		return fmt.Errorf("writing data values: %w", err)
+	}
+	if err := writeFile(filepath.Join(c.dir, plugin.ContextFileDataValuesJSON), dataJSON); err != nil {
+		return fmt.Errorf("writing data values: %w", err)
+	}
+	return nil
+}
+
+// writeRenderedFiles lists the files of a rendered directory, one slash-separated relative path per line.
+// A missing directory results in an empty list.
+func (c *pluginContext) writeRenderedFiles(renderedDir string) error {
+	var files []string
+	err := filepath.WalkDir(renderedDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(renderedDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel)+"\n")
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("listing rendered files: %w", err)
+	}
+	slices.Sort(files)
+	return writeFile(filepath.Join(c.dir, plugin.ContextFileRenderedFiles), []byte(strings.Join(files, "")))
+}
+
+// writeSourceFiles writes the source files of the rendering steps, keyed by step name.
+func (c *pluginContext) writeSourceFiles(stepFiles map[string][]string) error {
+	data, err := json.MarshalIndent(stepFiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling source files: %w", err)
+	}
+	return writeFile(filepath.Join(c.dir, plugin.ContextFileSourceFiles), data)
+}
+
+// writeAppPluginContext writes the data values, rendered files, and source files of an application.
+func writeAppPluginContext(c *pluginContext, a *Application, dataValues []byte) error {
+	if err := c.writeDataValues(dataValues); err != nil {
+		return err
+	}
+	if err := c.writeRenderedFiles(a.getDestinationDir()); err != nil {
+		return err
+	}
+	stepFiles, err := a.inspectStepFiles()
+	if err != nil {
+		return err
+	}
+	return c.writeSourceFiles(stepFiles)
+}
+
+// setPluginDataValuesEnv sets MYKS_DATA_VALUES unless the data values exceed the size limit of environment variables.
+// The data values are always available in the context directory.
+func setPluginDataValuesEnv(env map[string]string, dataValues []byte) {
+	if len(dataValues) > pluginDataValuesEnvLimit {
+		log.Warn().Int("size", len(dataValues)).Msg("Data values are too large for MYKS_DATA_VALUES, passing them in MYKS_CONTEXT_DIR only")
+		return
+	}
+	env[plugin.EnvVarDataValues] = string(dataValues)
+}
@@ -0,0 +1,98 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/pkg/plugin"
+)
+
+func Test_pluginContext(t *testing.T) {
+	t.Parallel()
+
+	c, err := newPluginContext()
+	require.NoError(t, err)
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(c.dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	require.NoError(t, c.writeDataValues([]byte("application:\n  name: app\n")))
+	assert.Equal(t, "application:\n  name: app\n", read(plugin.ContextFileDataValuesYAML))
+	assert.JSONEq(t, `{"application": {"name": "app"}}`, read(plugin.ContextFileDataValuesJSON))
+
+	renderedDir := t.TempDir()
+	require.NoError(t, writeFile(filepath.Join(renderedDir, "static", "file.txt"), nil))
+	require.NoError(t, writeFile(filepath.Join(renderedDir, "deployment-app.yaml"), nil))
+	require.NoError(t, c.writeRenderedFiles(renderedDir))
+	assert.Equal(t, "deployment-app.yaml\nstatic/file.txt\n", read(plugin.ContextFileRenderedFiles))
+	require.NoError(t, c.writeRenderedFiles(filepath.Join(renderedDir, "missing")))
+	assert.Empty(t, read(plugin.ContextFileRenderedFiles))
+
+	require.NoError(t, c.writeSourceFiles(map[string][]string{"render-ytt": {"envs/dev/_apps/app/ytt/app.yaml"}}))
+	assert.JSONEq(t, `{"render-ytt": ["envs/dev/_apps/app/ytt/app.yaml"]}`, read(plugin.ContextFileSourceFiles))
+
+	c.remove()
+	_, err = os.Stat(c.dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_setPluginDataValuesEnv(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{}
+	setPluginDataValuesEnv(env, []byte("a: b\n"))
+	assert.Equal(t, "a: b\n", env["MYKS_DATA_VALUES"])
+
+	env = map[string]string{}
+	setPluginDataValuesEnv(env, []byte(strings.Repeat("b", pluginDataValuesEnvLimit)))
+	assert.Len(t, env["MYKS_DATA_VALUES"], 128*1024-len("MYKS_DATA_VALUES=\x00"), "data values up to the limit are passed")
+
+	env = map[string]string{}
+	setPluginDataValuesEnv(env, []byte("a: "+strings.Repeat("b", pluginDataValuesEnvLimit)))
+	assert.NotContains(t, env, "MYKS_DATA_VALUES", "large data values are only passed in the context directory")
+}
+
+func TestPluginCmd_ExecEnv_contextDir(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	env := &Environment{ID: "dev", Dir: "envs/dev", cfg: &g.Config, g: g, dataValuesYaml: []byte("environment:\n  id: dev\n")}
+
+	out := filepath.Join(g.RootDir, "out")
+	script := filepath.Join(g.RootDir, "record")
+	content := "#!/bin/sh\ncat \"$MYKS_CONTEXT_DIR/" + plugin.ContextFileDataValuesJSON + "\" > " + out + "\n"
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o700)) // #nosec G306 -- plugin executable
+
+	p := &PluginCmd{name: "record", cmd: script, manifest: PluginManifest{Scope: PluginScopeEnv}}
+	require.NoError(t, p.ExecEnv(env, nil, true))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"environment": {"id": "dev"}}`, string(data))
+}
+
+func TestPluginCmd_ExecGlobal_rootDir(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = "."
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "record")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nprintf %s \"$MYKS_ROOT_DIR\" > "+out+"\n"), 0o700)) // #nosec G306 -- plugin executable
+
+	p := &PluginCmd{name: "record", cmd: script, manifest: PluginManifest{Scope: PluginScopeGlobal}}
+	require.NoError(t, p.ExecGlobal(g, nil, true))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, wd, string(data), "the root directory is passed as an absolute path")
+}
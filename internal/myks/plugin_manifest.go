@@ -0,0 +1,115 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// pluginManifestSuffix is appended to the plugin executable path, without its extension, to find the plugin manifest.
+const pluginManifestSuffix = ".plugin.yaml"
+
+// Plugin execution scopes
+const (
+	// PluginScopeApp runs a plugin once per application
+	PluginScopeApp = "app"
+	// PluginScopeEnv runs a plugin once per environment
+	PluginScopeEnv = "env"
+	// PluginScopeGlobal runs a plugin once for all environments
+	PluginScopeGlobal = "global"
+)
+
+// Plugin flag types
+const (
+	PluginFlagTypeString = "string"
+	PluginFlagTypeBool   = "bool"
+)
+
+// PluginManifest describes a plugin in a sidecar file next to its executable.
+// All fields are optional.
+type PluginManifest struct {
+	// One-line description shown in the list of commands
+	Description string `json:"description,omitempty"`
+	// Detailed description shown in the help of the plugin command
+	Long string `json:"long,omitempty"`
+	// Flags forwarded to the plugin as plugin arguments
+	Flags []PluginFlag `json:"flags,omitempty"`
+	// Completion candidates of plugin arguments, passed after `--`
+	Args []string `json:"args,omitempty"`
+	// Minimum version of myks required by the plugin
+	MinVersion string `json:"min-version,omitempty"`
+	// Execution scope: app (default), env, or global
+	Scope string `json:"scope,omitempty"`
+}
+
+// PluginFlag is a flag declared by a plugin.
+type PluginFlag struct {
+	Name        string `json:"name"`
+	Shorthand   string `json:"shorthand,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Flag type: string (default) or bool
+	Type    string `json:"type,omitempty"`
+	Default string `json:"default,omitempty"`
+	// Completion candidates of the flag value
+	Values []string `json:"values,omitempty"`
+}
+
+// pluginManifestPath returns the path of the manifest of a plugin executable, e.g. `plugins/kapp.plugin.yaml`.
+func pluginManifestPath(cmd string) string {
+	return strings.TrimSuffix(cmd, filepath.Ext(cmd)) + pluginManifestSuffix
+}
+
+// LoadPluginManifest reads the manifest of a plugin executable.
+// A missing manifest results in an empty manifest with default values.
+func LoadPluginManifest(cmd string) (PluginManifest, error) {
+	manifest := PluginManifest{Scope: PluginScopeApp}
+	path := pluginManifestPath(cmd)
+	data, err := os.ReadFile(path) // #nosec G304 -- the manifest is next to a user-provided plugin
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, fmt.Errorf("reading plugin manifest: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return PluginManifest{Scope: PluginScopeApp}, fmt.Errorf("parsing plugin manifest %s: %w", path, err)
+	}
+	if manifest.Scope == "" {
+		manifest.Scope = PluginScopeApp
+	}
+	if err := manifest.validate(); err != nil {
+		return PluginManifest{Scope: PluginScopeApp}, fmt.Errorf("invalid plugin manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+func (m PluginManifest) validate() error {
+	switch m.Scope {
+	case PluginScopeApp, PluginScopeEnv, PluginScopeGlobal:
+	default:
+		return fmt.Errorf("unsupported scope %q", m.Scope)
+	}
+	seen := map[string]bool{}
+	for _, flag := range m.Flags {
+		if flag.Name == "" {
+			return errors.New("flag without a name")
+		}
+		if seen[flag.Name] {
+			return fmt.Errorf("duplicate flag %q", flag.Name)
+		}
+		seen[flag.Name] = true
+		if len(flag.Shorthand) > 1 {
+			return fmt.Errorf("shorthand of flag %q must be a single character", flag.Name)
+		}
+		switch flag.Type {
+		case "", PluginFlagTypeString, PluginFlagTypeBool:
+		default:
+			return fmt.Errorf("unsupported type %q of flag %q", flag.Type, flag.Name)
+		}
+	}
+	return nil
+}
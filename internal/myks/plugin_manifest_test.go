@@ -0,0 +1,108 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPluginManifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	manifest, err := LoadPluginManifest(filepath.Join(dir, "plain"))
+	require.NoError(t, err)
+	assert.Equal(t, PluginManifest{Scope: PluginScopeApp}, manifest, "a missing manifest results in defaults")
+
+	write("kapp.plugin.yaml", `
+description: Deploy with kapp
+min-version: v5.0.0
+args: [--diff-changes]
+flags:
+  - name: wait
+    type: bool
+    description: wait for resources
+  - name: output
+    shorthand: o
+    values: [text, json]
+`)
+	manifest, err = LoadPluginManifest(filepath.Join(dir, "kapp.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, PluginManifest{
+		Description: "Deploy with kapp",
+		MinVersion:  "v5.0.0",
+		Args:        []string{"--diff-changes"},
+		Scope:       PluginScopeApp,
+		Flags: []PluginFlag{
+			{Name: "wait", Type: PluginFlagTypeBool, Description: "wait for resources"},
+			{Name: "output", Shorthand: "o", Values: []string{"text", "json"}},
+		},
+	}, manifest)
+
+	invalid := map[string]string{
+		"unknown-field": "descripton: typo",
+		"scope":         "scope: cluster",
+		"no-name":       "flags: [{type: bool}]",
+		"duplicate":     "flags: [{name: a}, {name: a}]",
+		"shorthand":     "flags: [{name: a, shorthand: ab}]",
+		"type":          "flags: [{name: a, type: int}]",
+	}
+	for name, content := range invalid {
+		write(name+".plugin.yaml", content)
+		manifest, err := LoadPluginManifest(filepath.Join(dir, name))
+		require.Error(t, err, name)
+		assert.Equal(t, PluginManifest{Scope: PluginScopeApp}, manifest, name)
+	}
+}
+
+func TestFindPluginsInPaths_manifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "myks-lint"), []byte("#!/bin/sh\n"), 0o700)) // #nosec G306 -- plugin executable
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "myks-lint.plugin.yaml"), []byte("description: Lint manifests\n"), 0o600))
+
+	plugins := FindPluginsInPaths([]string{dir}, "myks-")
+	require.Len(t, plugins, 1, "the manifest is not a plugin")
+	assert.Equal(t, "lint", plugins[0].Name())
+	assert.Equal(t, "Lint manifests", plugins[0].Manifest().Description)
+}
+
+func TestGlobe_ExecPlugin_scopes(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{}
+	for _, id := range []string{"prod", "dev"} {
+		env := &Environment{ID: id, Dir: "envs/" + id, cfg: &g.Config, g: g, initialized: true, dataValuesYaml: []byte("environment: {id: " + id + "}\n")}
+		env.Applications = []*Application{{Name: "b", e: env}, {Name: "a", e: env}}
+		g.environments[env.Dir] = env
+	}
+
+	out := filepath.Join(g.RootDir, "out")
+	script := filepath.Join(g.RootDir, "record")
+	content := "#!/bin/sh\necho \"$MYKS_ENVS|$MYKS_ENV|$MYKS_APPS|$MYKS_DATA_VALUES\" | head -n 1 >> " + out + "\n"
+	require.NoError(t, os.WriteFile(script, []byte(content), 0o700)) // #nosec G306 -- plugin executable
+	run := func(scope string) []string {
+		require.NoError(t, os.RemoveAll(out))
+		p := &PluginCmd{name: "record", cmd: script, manifest: PluginManifest{Scope: scope}}
+		require.NoError(t, g.ExecPlugin(1, p, nil, true))
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	assert.Equal(t, []string{"dev,prod|||"}, run(PluginScopeGlobal))
+	assert.ElementsMatch(t, []string{"|dev|a,b|environment: {id: dev}", "|prod|a,b|environment: {id: prod}"}, run(PluginScopeEnv))
+}
@@ -7,21 +7,32 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"github.com/mykso/myks/pkg/plugin"
 )
 
 // Plugin is the interface implemented by external plugin commands.
 type Plugin interface {
+	// Exec runs the plugin for an application
 	Exec(a *Application, args []string, bufferOutput bool) error
+	// ExecEnv runs the plugin for an environment and its selected applications
+	ExecEnv(e *Environment, args []string, bufferOutput bool) error
+	// ExecGlobal runs the plugin once for all selected environments
+	ExecGlobal(g *Globe, args []string, bufferOutput bool) error
 	Name() string
+	Manifest() PluginManifest
 }
 
 type PluginCmd struct {
-	name string
-	cmd  string
+	name     string
+	cmd      string
+	manifest PluginManifest
 }
 
 // Ensure PluginCmd implements the Plugin interface
@@ -30,9 +41,14 @@ var _ Plugin = &PluginCmd{}
 func NewPluginFromCmd(cmd, filePrefix string) Plugin {
 	name := strings.TrimPrefix(filepath.Base(cmd), filePrefix)
 	name = strings.TrimSuffix(name, filepath.Ext(name))
+	manifest, err := LoadPluginManifest(cmd)
+	if err != nil {
+		log.Warn().Err(err).Str("plugin", name).Msg("Ignoring the plugin manifest")
+	}
 	return &PluginCmd{
-		name: name,
-		cmd:  cmd,
+		name:     name,
+		cmd:      cmd,
+		manifest: manifest,
 	}
 }
 
@@ -96,24 +112,85 @@ func (p PluginCmd) Name() string {
 	return p.name
 }
 
+// Manifest returns the metadata declared in the plugin manifest.
+func (p PluginCmd) Manifest() PluginManifest {
+	return p.manifest
+}
+
 func (p PluginCmd) Exec(a *Application, args []string, bufferOutput bool) error {
 	step := p.Name()
 	log.Trace().Msg(a.Msg(step, "execution started"))
 
-	env, err := p.generateEnv(a)
+	c, err := newPluginContext()
+	if err != nil {
+		return err
+	}
+	defer c.remove()
+
+	env, err := p.generateEnv(a, c)
 	if err != nil {
 		log.Error().Err(err).Msg(a.Msg(step, "Generating data values failed"))
 		return err
 	}
 
-	cmd := exec.Command(p.cmd, args...) // #nosec G204 -- this is a user-provided command
-	cmd.Env = append(os.Environ(), mapToSlice(env)...)
 	log.Debug().Msg(a.Msg(step, msgRunCmd("", p.cmd, args)))
+	return p.run(env, args, bufferOutput)
+}
+
+func (p PluginCmd) ExecEnv(e *Environment, args []string, bufferOutput bool) error {
+	log.Trace().Msg(e.Msg(p.Name() + " execution started"))
+	c, err := newPluginContext()
+	if err != nil {
+		return err
+	}
+	defer c.remove()
+	if err := c.writeDataValues(e.dataValuesYaml); err != nil {
+		log.Error().Err(err).Msg(e.Msg("Writing plugin context failed"))
+		return err
+	}
+
+	appNames := e.GetApplicationNames()
+	slices.Sort(appNames)
+	env := map[string]string{
+		plugin.EnvVarEnv:            e.ID,
+		plugin.EnvVarEnvDir:         e.Dir,
+		plugin.EnvVarRenderedEnvDir: filepath.Join(e.cfg.RootDir, e.cfg.RenderedEnvsDir, e.ID),
+		plugin.EnvVarApps:           strings.Join(appNames, ","),
+		plugin.EnvVarContextDir:     c.dir,
+	}
+	setPluginDataValuesEnv(env, e.dataValuesYaml)
+	log.Debug().Msg(e.Msg(msgRunCmd("", p.cmd, args)))
+	return p.run(env, args, bufferOutput)
+}
+
+func (p PluginCmd) ExecGlobal(g *Globe, args []string, bufferOutput bool) error {
+	log.Trace().Msg(g.Msg(p.Name() + " execution started"))
+	var envIDs []string
+	for _, e := range g.getInitializedEnvironments() {
+		envIDs = append(envIDs, e.ID)
+	}
+	slices.Sort(envIDs)
+	// The root directory is usually ".", as myks changes to it on start
+	rootDir, err := filepath.Abs(g.RootDir)
+	if err != nil {
+		return fmt.Errorf("resolving root directory: %w", err)
+	}
+	env := map[string]string{
+		plugin.EnvVarRootDir: rootDir,
+		plugin.EnvVarEnvs:    strings.Join(envIDs, ","),
+	}
+	log.Debug().Msg(g.Msg(msgRunCmd("", p.cmd, args)))
+	return p.run(env, args, bufferOutput)
+}
 
+func (p PluginCmd) run(env map[string]string, args []string, bufferOutput bool) error {
+	cmd := exec.Command(p.cmd, args...) // #nosec G204 -- this is a user-provided command
+	env[plugin.EnvVarLogLevel] = zerolog.GlobalLevel().String()
+	cmd.Env = append(os.Environ(), mapToSlice(env)...)
 	if bufferOutput {
-		return p.runBuffered(step, cmd, args)
+		return p.runBuffered(p.Name(), cmd, args)
 	}
-	return p.runStreaming(step, cmd, args)
+	return p.runStreaming(p.Name(), cmd, args)
 }
 
 func (p PluginCmd) runBuffered(step string, cmd *exec.Cmd, args []string) error {
@@ -152,18 +229,20 @@ func (p PluginCmd) runStreaming(step string, cmd *exec.Cmd, args []string) error
 	return err
 }
 
-func (p PluginCmd) generateEnv(a *Application) (map[string]string, error) {
+func (p PluginCmd) generateEnv(a *Application, c *pluginContext) (map[string]string, error) {
 	env := map[string]string{
-		"MYKS_ENV":              a.e.ID,
-		"MYKS_APP":              a.Name,
-		"MYKS_APP_PROTOTYPE":    a.Prototype,
-		"MYKS_ENV_DIR":          a.e.Dir,
-		"MYKS_RENDERED_APP_DIR": a.getDestinationDir(),
+		plugin.EnvVarEnv:            a.e.ID,
+		plugin.EnvVarApp:            a.Name,
+		plugin.EnvVarAppPrototype:   a.Prototype,
+		plugin.EnvVarEnvDir:         a.e.Dir,
+		plugin.EnvVarRenderedAppDir: a.getDestinationDir(),
+		plugin.EnvVarContextDir:     c.dir,
 	}
 
 	result, err := a.ytt(p.Name(), "get data values", a.yttDataFiles, "--data-values-inspect")
-	if err == nil {
-		env["MYKS_DATA_VALUES"] = result.Stdout
+	if err != nil {
+		return env, err
 	}
-	return env, err
+	setPluginDataValuesEnv(env, []byte(result.Stdout))
+	return env, writeAppPluginContext(c, a, []byte(result.Stdout))
 }
@@ -2,6 +2,7 @@ package myks
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"os/exec"
@@ -41,7 +42,12 @@ func msgRunCmd(purpose, cmd string, args []string) string {
 }
 
 func runCmd(step, name string, stdin io.Reader, args []string, logFn func(name string, err error, stderr string, args []string)) (CmdResult, error) {
-	cmd := exec.Command(name, args...) // #nosec G204 -- command names are controlled internally (embedded tools)
+	return runCmdContext(context.Background(), step, name, stdin, args, logFn)
+}
+
+// runCmdContext is runCmd that kills the command when the context is done.
+func runCmdContext(ctx context.Context, step, name string, stdin io.Reader, args []string, logFn func(name string, err error, stderr string, args []string)) (CmdResult, error) {
+	cmd := exec.CommandContext(ctx, name, args...) // #nosec G204 -- command names are controlled internally (embedded tools)
 
 	if stdin != nil {
 		cmd.Stdin = stdin
@@ -196,10 +196,7 @@ func genRenderedResourceFileName(resource map[string]any, includeNamespace bool)
 func (a *Application) prepareValuesFile(dirName, chartName string) (string, error) {
 	yttArgs := []string{"-v", "myks.context.helm.chart=" + chartName}
 
-	valuesFiles := slices.Concat(
-		a.collectAllFilesByGlob(filepath.Join(dirName, "_global.*yaml")),
-		a.collectAllFilesByGlob(filepath.Join(dirName, chartName+".*yaml")),
-	)
+	valuesFiles := a.valuesSourceFiles(dirName, chartName)
 
 	if len(valuesFiles) == 0 {
 		log.Debug().Str("resource", chartName).Msg(a.Msg(renderStepName, "No values files found"))
@@ -240,6 +237,15 @@ func (a *Application) prepareValuesFile(dirName, chartName string) (string, erro
 	return a.expandServicePath(valuesFileName), err
 }
 
+// valuesSourceFiles returns the values files used by prepareValuesFile for the given resource,
+// ordered from the lowest to the highest precedence: global values first, then resource-specific values.
+func (a *Application) valuesSourceFiles(dirName, resourceName string) []string {
+	return slices.Concat(
+		a.collectAllFilesByGlob(filepath.Join(dirName, "_global.*yaml")),
+		a.collectAllFilesByGlob(filepath.Join(dirName, resourceName+".*yaml")),
+	)
+}
+
 func (a *Application) collectFilesByGlob(subpathPattern string) ([]string, error) {
 	var files []string
 	currentPath := a.cfg.RootDir
@@ -286,6 +292,16 @@ func (a *Application) collectAllFilesByGlob(pattern string) []string {
 	return slices.Concat(protoFiles, protoOverrideFiles, files)
 }
 
+// acquireServiceDirLock acquires a write lock on the service directory of the application.
+// The lock name is the absolute path of the directory, which is unique across projects.
+func (a *Application) acquireServiceDirLock(lock *locker.Locker) (func(), error) {
+	serviceDir, err := filepath.Abs(a.expandServicePath(""))
+	if err != nil {
+		return nil, fmt.Errorf("resolving service directory: %w", err)
+	}
+	return lock.LockNames(slices.Values([]string{"app:" + serviceDir}), true), nil
+}
+
 // AcquireRenderLock acquires a read or write lock on the vendor paths relevant to this application,
 // filtered by vendorFilter. Returns a release function and any error.
 func (a *Application) AcquireRenderLock(lock *locker.Locker, vendorFilter func(string) bool, forWrite bool) (func(), error) {
@@ -16,6 +16,8 @@ type Helm struct {
 	app      *Application
 	ident    string
 	locker   *locker.Locker
+	// helmConfig is loaded when the lock is acquired and reused for rendering
+	helmConfig *HelmConfig
 }
 
 // NewHelmRenderer creates a new Helm renderer for the given application and locker.
@@ -28,10 +30,26 @@ func NewHelmRenderer(app *Application, lock *locker.Locker) *Helm {
 	}
 }
 
-// AcquireLock acquires a read lock on the Helm charts vendor directory for this application.
+// AcquireLock acquires a read lock on the Helm charts vendor directory for this application,
+// as well as on vendored directories that contain charts configured with `helm.charts[].path`.
 func (h *Helm) AcquireLock() (func(), error) {
+	helmConfig, err := h.getHelmConfig()
+	if err != nil {
+		return nil, err
+	}
+	vendorPaths := []string{}
+	for _, chart := range helmConfig.Charts {
+		if first, rest, _ := strings.Cut(filepath.ToSlash(filepath.Clean(chart.Path)), "/"); first == h.app.cfg.VendorDirName && rest != "" {
+			vendorPaths = append(vendorPaths, rest)
+		}
+	}
 	return h.app.AcquireRenderLock(h.locker, func(path string) bool {
-		return strings.HasPrefix(path, h.app.cfg.HelmChartsDirName+"/")
+		if strings.HasPrefix(path, h.app.cfg.HelmChartsDirName+"/") {
+			return true
+		}
+		return slices.ContainsFunc(vendorPaths, func(vendorPath string) bool {
+			return vendorPath == path || strings.HasPrefix(vendorPath, path+"/")
+		})
 	}, false)
 }
 
@@ -47,14 +65,14 @@ func (h *Helm) Render(_ string) (string, error) {
 	log.Debug().Msg(h.app.Msg(h.getStepName(), "Starting"))
 	outputs := []string{}
 
-	chartsDirs, err := h.app.getHelmChartsDirs(h.getStepName())
+	helmConfig, err := h.getHelmConfig()
 	if err != nil {
+		log.Warn().Err(err).Msg(h.app.Msg(h.getStepName(), "Unable to get helm config"))
 		return "", err
 	}
 
-	helmConfig, err := h.getHelmConfig()
+	charts, err := h.app.getHelmCharts(h.getStepName(), &helmConfig)
 	if err != nil {
-		log.Warn().Err(err).Msg(h.app.Msg(h.getStepName(), "Unable to get helm config"))
 		return "", err
 	}
 
@@ -69,8 +87,8 @@ func (h *Helm) Render(_ string) (string, error) {
 	}
 
 	chartNames := []string{}
-	for _, chartDir := range chartsDirs {
-		chartName := filepath.Base(chartDir)
+	for _, chart := range charts {
+		chartName, chartDir := chart.Name, chart.Dir
 		chartNames = append(chartNames, chartName)
 		chartConfig := helmConfig.getChartConfig(chartName)
 		var helmValuesFile string
@@ -79,6 +97,10 @@ func (h *Helm) Render(_ string) (string, error) {
 			return "", err
 		}
 
+		if err = h.app.validateHelmValues(chartDir, chartName, helmValuesFile); err != nil {
+			return "", err
+		}
+
 		if chartConfig.ReleaseName == "" {
 			chartConfig.ReleaseName = chartName
 		}
@@ -157,12 +179,21 @@ func (a *Application) helmValuesSourceFiles() []string {
 }
 
 func (h *Helm) getHelmConfig() (HelmConfig, error) {
+	if h.helmConfig != nil {
+		return *h.helmConfig, nil
+	}
+
 	dataValuesYaml, err := h.app.ytt(h.getStepName(), "get helm config", h.app.yttDataFiles, "--data-values-inspect")
 	if err != nil {
 		return HelmConfig{}, err
 	}
 
-	return newHelmConfig(dataValuesYaml.Stdout)
+	helmConfig, err := newHelmConfig(dataValuesYaml.Stdout)
+	if err != nil {
+		return HelmConfig{}, err
+	}
+	h.helmConfig = &helmConfig
+	return helmConfig, nil
 }
 
 func (h *Helm) getStepName() string {
@@ -0,0 +1,278 @@
+package myks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	helmChartArchiveExt    = ".tgz"
+	helmChartArchivesDir   = "helm-archives"
+	helmChartArchiveStamp  = ".archive-hash"
+	maxHelmChartFileSizeMB = 64
+)
+
+// helmChart is a Helm chart to be built and rendered for an application.
+type helmChart struct {
+	// Name of the chart, used to match values files and per-chart configuration
+	Name string
+	// Dir is the chart directory passed to helm
+	Dir string
+	// VendorPath is the chart location relative to the vendor directory.
+	// Empty for charts that do not come from the vendor directory, including vendored
+	// archives: they are extracted to the service directory of every application.
+	VendorPath string
+}
+
+// getHelmCharts returns the charts of the application, sorted by name.
+// Charts are discovered in the vendored charts directory. Additionally, every
+// `helm.charts[].path` points to a chart located elsewhere: in the prototype,
+// in a vendored sub-path, or in a `.tgz` archive. A configured path takes
+// precedence over a vendored chart with the same name.
+func (a *Application) getHelmCharts(stepName string, helmConfig *HelmConfig) ([]helmChart, error) {
+	charts, err := a.getVendoredHelmCharts(stepName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(helmConfig.Charts)) {
+		path := helmConfig.Charts[name].Path
+		if path == "" {
+			continue
+		}
+		chart, err := a.resolveHelmChartPath(name, path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving helm.charts[%s].path: %w", name, err)
+		}
+		charts = slices.DeleteFunc(charts, func(c helmChart) bool {
+			if c.Name != name {
+				return false
+			}
+			log.Debug().Str("chart", name).Msg(a.Msg(stepName, "Vendored chart is overridden by .helm.charts[].path"))
+			return true
+		})
+		charts = append(charts, chart)
+	}
+
+	slices.SortFunc(charts, func(x, y helmChart) int {
+		return strings.Compare(x.Name, y.Name)
+	})
+	if len(charts) == 0 {
+		log.Debug().Msg(a.Msg(stepName, "No Helm charts found"))
+	}
+	return charts, nil
+}
+
+func (a *Application) getVendoredHelmCharts(stepName string) ([]helmChart, error) {
+	baseDir := a.expandVendorPath(a.cfg.HelmChartsDirName)
+	if ok, err := isExist(baseDir); err != nil || !ok {
+		return nil, err
+	}
+	files, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	charts := []helmChart{}
+	for _, file := range files {
+		chartDir := filepath.Join(baseDir, file.Name())
+		if err = ensureValidChartEntry(chartDir); err != nil {
+			log.Warn().Err(err).Msg(a.Msg(stepName, "Skipping invalid chart entry"))
+			continue
+		}
+		charts = append(charts, helmChart{
+			Name:       file.Name(),
+			Dir:        chartDir,
+			VendorPath: filepath.Join(a.cfg.HelmChartsDirName, file.Name()),
+		})
+	}
+	return charts, nil
+}
+
+// resolveHelmChartPath resolves a configured chart path.
+// Paths starting with the vendor directory name (e.g. `vendor/repo/deploy/chart`)
+// are resolved against the vendor directory of the application, all other paths
+// against the prototype directory. Paths ending with `.tgz` are chart archives
+// that are extracted to the service directory of the application.
+func (a *Application) resolveHelmChartPath(name, path string) (helmChart, error) {
+	if filepath.IsAbs(path) {
+		return helmChart{}, fmt.Errorf("path must be relative: %s", path)
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return helmChart{}, fmt.Errorf("path must not leave its base directory: %s", path)
+	}
+
+	chart := helmChart{Name: name}
+	if first, rest, _ := strings.Cut(path, string(filepath.Separator)); first == a.cfg.VendorDirName && rest != "" {
+		chart.Dir = a.expandServicePath(path)
+		chart.VendorPath = rest
+	} else {
+		chart.Dir = filepath.Join(a.Prototype, path)
+	}
+
+	if strings.HasSuffix(chart.Dir, helmChartArchiveExt) {
+		dir, err := a.extractHelmChartArchive(name, chart.Dir)
+		if err != nil {
+			return helmChart{}, err
+		}
+		chart.Dir = dir
+		// The extracted copy belongs to the application, dependencies are built per chart directory
+		chart.VendorPath = ""
+	}
+
+	if err := ensureValidChartEntry(chart.Dir); err != nil {
+		return helmChart{}, fmt.Errorf("invalid chart at %s: %w", chart.Dir, err)
+	}
+	return chart, nil
+}
+
+// extractHelmChartArchive extracts a chart archive to the service directory and returns the chart directory.
+// The archive is only extracted again when its content changes, so that dependencies built
+// during sync are kept for rendering.
+func (a *Application) extractHelmChartArchive(name, archivePath string) (string, error) {
+	hash, err := hashFile(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	destDir := a.expandServicePath(filepath.Join(helmChartArchivesDir, name))
+	stampFile := filepath.Join(destDir, helmChartArchiveStamp)
+	if stamp, err := os.ReadFile(stampFile); err == nil && string(stamp) == hash {
+		if chartDir, err := findArchivedChartDir(destDir); err == nil {
+			return chartDir, nil
+		}
+	}
+
+	if err = os.RemoveAll(destDir); err != nil {
+		return "", err
+	}
+	if err = extractTarGz(archivePath, destDir); err != nil {
+		return "", fmt.Errorf("extracting chart archive %s: %w", archivePath, err)
+	}
+	if err = writeFile(stampFile, []byte(hash)); err != nil {
+		return "", err
+	}
+	return findArchivedChartDir(destDir)
+}
+
+// findArchivedChartDir returns the single top-level directory of an extracted chart archive.
+func findArchivedChartDir(dir string) (string, error) {
+	subDirs, err := getSubDirs(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(subDirs) != 1 {
+		return "", fmt.Errorf("expected exactly one chart directory in the archive, found %d", len(subDirs))
+	}
+	return subDirs[0], nil
+}
+
+func extractTarGz(archivePath, destDir string) error {
+	file, err := os.Open(filepath.Clean(archivePath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close file")
+		}
+	}()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	root, err := openRootCreate(destDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := root.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close directory")
+		}
+	}()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = root.MkdirAll(name, 0o750); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = root.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+				return err
+			}
+			if err = extractTarFile(root, name, tr); err != nil {
+				return err
+			}
+		default:
+			log.Debug().Str("entry", header.Name).Msg("Skipping unsupported chart archive entry")
+		}
+	}
+}
+
+func extractTarFile(root *os.Root, name string, r io.Reader) error {
+	out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	limit := int64(maxHelmChartFileSizeMB) << 20
+	n, err := io.Copy(out, io.LimitReader(r, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("file %s exceeds %d MiB", name, maxHelmChartFileSizeMB)
+	}
+	return err
+}
+
+func openRootCreate(dir string) (*os.Root, error) {
+	if err := createDirectory(dir); err != nil {
+		return nil, err
+	}
+	return os.OpenRoot(dir)
+}
+
+// findCacheNameForChart looks up the vendir cache that contains the given chart vendor path
+// (e.g. "charts/nginx") by searching the links map for the longest matching vendor path.
+// Returns the cache name and the path of the chart inside the cached directory,
+// which is empty when the chart is the cached directory itself.
+func findCacheNameForChart(linksMap map[string]string, vendorPath string) (string, string) {
+	if vendorPath == "" {
+		return "", ""
+	}
+	bestPath, bestCache := "", ""
+	for path, cacheName := range linksMap {
+		if path != vendorPath && !strings.HasPrefix(vendorPath, path+string(filepath.Separator)) {
+			continue
+		}
+		if len(path) > len(bestPath) {
+			bestPath, bestCache = path, cacheName
+		}
+	}
+	if bestCache == "" {
+		return "", ""
+	}
+	subPath := strings.TrimPrefix(strings.TrimPrefix(vendorPath, bestPath), string(filepath.Separator))
+	return bestCache, subPath
+}
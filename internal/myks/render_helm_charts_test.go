@@ -0,0 +1,155 @@
+package myks
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHelmChartsTestApp(t *testing.T) *Application {
+	t.Helper()
+	rootDir := t.TempDir()
+	cfg := &Config{
+		RootDir:           rootDir,
+		ServiceDirName:    ".myks",
+		AppsDir:           "_apps",
+		VendorDirName:     "vendor",
+		HelmChartsDirName: "charts",
+	}
+	return &Application{
+		Name:      "app",
+		Prototype: filepath.Join(rootDir, "prototypes", "proto"),
+		cfg:       cfg,
+		e:         &Environment{ID: "env", Dir: filepath.Join("envs", "env")},
+	}
+}
+
+func writeTestChart(t *testing.T, dir string) {
+	t.Helper()
+	writeTestFile(t, filepath.Join(dir, "Chart.yaml"), "apiVersion: v2\nname: "+filepath.Base(dir)+"\nversion: 1.0.0\n")
+}
+
+func writeTestChartArchive(t *testing.T, path, chartName string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string]string{
+		chartName + "/Chart.yaml":  "apiVersion: v2\nname: " + chartName + "\nversion: 1.0.0\n",
+		chartName + "/values.yaml": "replicas: 1\n",
+	}
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+}
+
+func TestApplication_getHelmCharts(t *testing.T) {
+	t.Parallel()
+
+	app := newHelmChartsTestApp(t)
+	writeTestChart(t, app.expandVendorPath(filepath.Join("charts", "vendored")))
+	writeTestChart(t, app.expandVendorPath(filepath.Join("charts", "overridden")))
+	writeTestChart(t, filepath.Join(app.Prototype, "charts", "local"))
+	writeTestChart(t, app.expandVendorPath(filepath.Join("repo", "deploy", "chart")))
+	writeTestChartArchive(t, filepath.Join(app.Prototype, "charts", "archived-1.0.0.tgz"), "archived")
+	writeTestChartArchive(t, app.expandVendorPath(filepath.Join("archives", "vendored-1.0.0.tgz")), "vendored-archive")
+
+	helmConfig := &HelmConfig{Charts: map[string]HelmChartOverride{
+		"local":            {Path: "charts/local"},
+		"overridden":       {Path: "vendor/repo/deploy/chart"},
+		"archive":          {Path: "charts/archived-1.0.0.tgz"},
+		"vendored-archive": {Path: "vendor/archives/vendored-1.0.0.tgz"},
+		"no-path":          {Namespace: "ns"},
+	}}
+
+	charts, err := app.getHelmCharts("test", helmConfig)
+	require.NoError(t, err)
+
+	expected := []helmChart{
+		{
+			Name: "archive",
+			Dir:  app.expandServicePath(filepath.Join(helmChartArchivesDir, "archive", "archived")),
+		},
+		{
+			Name: "local",
+			Dir:  filepath.Join(app.Prototype, "charts", "local"),
+		},
+		{
+			Name:       "overridden",
+			Dir:        app.expandVendorPath(filepath.Join("repo", "deploy", "chart")),
+			VendorPath: filepath.Join("repo", "deploy", "chart"),
+		},
+		{
+			Name:       "vendored",
+			Dir:        app.expandVendorPath(filepath.Join("charts", "vendored")),
+			VendorPath: filepath.Join("charts", "vendored"),
+		},
+		{
+			// Extracted archives are not shared with other applications through the vendir cache
+			Name: "vendored-archive",
+			Dir:  app.expandServicePath(filepath.Join(helmChartArchivesDir, "vendored-archive", "vendored-archive")),
+		},
+	}
+	assert.Equal(t, expected, charts)
+	assert.FileExists(t, filepath.Join(charts[0].Dir, "values.yaml"))
+}
+
+func TestApplication_getHelmCharts_invalidPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"absolute path", "/charts/chart"},
+		{"path outside of base directory", "../other/charts/chart"},
+		{"missing chart", "charts/missing"},
+		{"missing archive", "charts/missing.tgz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app := newHelmChartsTestApp(t)
+			helmConfig := &HelmConfig{Charts: map[string]HelmChartOverride{"chart": {Path: tt.path}}}
+			_, err := app.getHelmCharts("test", helmConfig)
+			assert.ErrorContains(t, err, "helm.charts[chart].path")
+		})
+	}
+}
+
+func TestApplication_extractHelmChartArchive_keepsUnchangedArchive(t *testing.T) {
+	t.Parallel()
+
+	app := newHelmChartsTestApp(t)
+	archive := filepath.Join(app.Prototype, "chart.tgz")
+	writeTestChartArchive(t, archive, "chart")
+
+	chartDir, err := app.extractHelmChartArchive("chart", archive)
+	require.NoError(t, err)
+
+	// Simulate dependencies built during sync
+	builtDep := filepath.Join(chartDir, "charts", "dep.tgz")
+	writeTestFile(t, builtDep, "dep")
+
+	chartDir, err = app.extractHelmChartArchive("chart", archive)
+	require.NoError(t, err)
+	assert.FileExists(t, builtDep)
+
+	writeTestChartArchive(t, archive, "chart-v2")
+	chartDir, err = app.extractHelmChartArchive("chart", archive)
+	require.NoError(t, err)
+	assert.Equal(t, "chart-v2", filepath.Base(chartDir))
+	assert.NoFileExists(t, builtDep)
+}
@@ -0,0 +1,243 @@
+package myks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	helmValuesSchemaFileName = "values.schema.json"
+	helmChartValuesFileName  = "values.yaml"
+)
+
+// helmSchemaViolation describes a single values.schema.json violation.
+type helmSchemaViolation struct {
+	// JSON pointer to the offending value, e.g. /image/tag
+	Path string
+	// Human-readable description of the violation
+	Message string
+	// myks source file (with line) that set the offending value
+	Source string
+
+	tokens []string
+}
+
+// validateHelmValues validates the merged values file of a chart against the chart's values.schema.json.
+// Helm performs the same validation during `helm template`, but its errors refer to the merged temporary file.
+// Here, every violation is traced back to the myks values file that set the offending key.
+// Returns nil if the chart has no schema or there are no values to validate.
+func (a *Application) validateHelmValues(chartDir, chartName, valuesFile string) error {
+	if valuesFile == "" {
+		return nil
+	}
+
+	schemaPath := filepath.Join(chartDir, helmValuesSchemaFileName)
+	if ok, err := isExist(schemaPath); err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	defaultsPath := filepath.Join(chartDir, helmChartValuesFileName)
+	violations, err := validateValuesAgainstSchema(schemaPath, defaultsPath, valuesFile)
+	// Only local files are loaded, e.g. a remote $ref of the schema can't be resolved
+	var loadErr *jsonschema.LoadURLError
+	if errors.As(err, &loadErr) {
+		log.Warn().Err(err).Str("chart", chartName).Msg(a.Msg(renderStepName, "Unable to load the chart schema, leaving the validation to helm"))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("validating values of chart %s against %s: %w", chartName, helmValuesSchemaFileName, err)
+	}
+	if len(violations) == 0 {
+		log.Debug().Str("chart", chartName).Msg(a.Msg(renderStepName, "Helm values match the chart schema"))
+		return nil
+	}
+
+	sourceFiles := a.valuesSourceFiles(a.cfg.HelmStepDirName, chartName)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "values of chart %s do not match %s:", chartName, helmValuesSchemaFileName)
+	for i := range violations {
+		v := &violations[i]
+		v.Source = locateValueSource(sourceFiles, defaultsPath, v.tokens)
+		fmt.Fprintf(&sb, "\n  - %s: %s (set in %s)", v.Path, v.Message, v.Source)
+	}
+	return errors.New(sb.String())
+}
+
+// validateValuesAgainstSchema coalesces the chart default values with the provided values file,
+// the same way Helm does, and validates the result against the JSON schema.
+func validateValuesAgainstSchema(schemaPath, defaultsPath, valuesPath string) ([]helmSchemaViolation, error) {
+	schema, err := jsonschema.NewCompiler().Compile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema: %w", err)
+	}
+
+	defaults, err := unmarshalYamlToMap(defaultsPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading chart default values: %w", err)
+	}
+	values, err := unmarshalYamlToMap(valuesPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading values: %w", err)
+	}
+
+	// Round-trip through JSON to get the value types the validator expects
+	data, err := json.Marshal(coalesceHelmValues(defaults, values))
+	if err != nil {
+		return nil, fmt.Errorf("converting values to JSON: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding values: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return collectSchemaViolations(validationErr), nil
+	}
+	return nil, err
+}
+
+// collectSchemaViolations flattens the validation error tree into its leaves, sorted by path.
+func collectSchemaViolations(validationErr *jsonschema.ValidationError) []helmSchemaViolation {
+	var violations []helmSchemaViolation
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+		out := e.BasicOutput()
+		message := ""
+		if out.Error != nil {
+			message = out.Error.String()
+		}
+		path := out.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		violations = append(violations, helmSchemaViolation{
+			Path:    path,
+			Message: message,
+			tokens:  slices.Clone(e.InstanceLocation),
+		})
+	}
+	walk(validationErr)
+
+	slices.SortStableFunc(violations, func(a, b helmSchemaViolation) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return violations
+}
+
+// coalesceHelmValues merges values over chart defaults, following Helm's rules:
+// maps are merged recursively, other values are replaced, and null values remove the default.
+func coalesceHelmValues(defaults, values map[string]any) map[string]any {
+	result := make(map[string]any, len(defaults)+len(values))
+	maps.Copy(result, defaults)
+	for k, v := range values {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		if valuesMap, ok := v.(map[string]any); ok {
+			if defaultsMap, ok := result[k].(map[string]any); ok {
+				result[k] = coalesceHelmValues(defaultsMap, valuesMap)
+				continue
+			}
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// locateValueSource finds the file that sets the value at the given path.
+// Values files are searched from the highest to the lowest precedence, then the chart defaults.
+// If the exact path is not set anywhere (e.g. a missing required property), the closest parent is reported.
+func locateValueSource(sourceFiles []string, defaultsPath string, tokens []string) string {
+	for depth := len(tokens); depth >= 0; depth-- {
+		for _, file := range slices.Backward(sourceFiles) {
+			if line, ok := findYamlPathLine(file, tokens[:depth]); ok {
+				return fmt.Sprintf("%s:%d", file, line)
+			}
+		}
+		if line, ok := findYamlPathLine(defaultsPath, tokens[:depth]); ok {
+			return fmt.Sprintf("chart defaults %s:%d", defaultsPath, line)
+		}
+	}
+	return "unknown source"
+}
+
+// findYamlPathLine returns the line of the key at the given path in any document of the YAML file.
+// ytt annotations are YAML comments, so ytt templates are parsed as plain YAML on a best-effort basis.
+// The last matching document wins, as it takes precedence when documents are merged.
+func findYamlPathLine(file string, tokens []string) (int, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+
+	line, found := 0, false
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if l, ok := findNodePathLine(&doc, tokens); ok {
+			line, found = l, true
+		}
+	}
+	return line, found
+}
+
+func findNodePathLine(node *yaml.Node, tokens []string) (int, bool) {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return 0, false
+		}
+		node = node.Content[0]
+	}
+	if len(tokens) == 0 {
+		return node.Line, node.Kind == yaml.MappingNode && len(node.Content) > 0
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != tokens[0] {
+				continue
+			}
+			if len(tokens) == 1 {
+				return node.Content[i].Line, true
+			}
+			return findNodePathLine(node.Content[i+1], tokens[1:])
+		}
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(tokens[0])
+		if err != nil || idx < 0 || idx >= len(node.Content) {
+			return 0, false
+		}
+		if len(tokens) == 1 {
+			return node.Content[idx].Line, true
+		}
+		return findNodePathLine(node.Content[idx], tokens[1:])
+	}
+	return 0, false
+}
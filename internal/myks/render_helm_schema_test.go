@@ -0,0 +1,192 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return path
+}
+
+func Test_coalesceHelmValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults map[string]any
+		values   map[string]any
+		want     map[string]any
+	}{
+		{
+			"nested maps are merged",
+			map[string]any{"image": map[string]any{"repository": "nginx", "tag": "1.0"}, "replicas": 1},
+			map[string]any{"image": map[string]any{"tag": "2.0"}},
+			map[string]any{"image": map[string]any{"repository": "nginx", "tag": "2.0"}, "replicas": 1},
+		},
+		{
+			"scalars and lists are replaced",
+			map[string]any{"replicas": 1, "args": []any{"a", "b"}},
+			map[string]any{"replicas": 3, "args": []any{"c"}},
+			map[string]any{"replicas": 3, "args": []any{"c"}},
+		},
+		{
+			"null removes default",
+			map[string]any{"resources": map[string]any{"cpu": "1"}, "replicas": 1},
+			map[string]any{"resources": nil},
+			map[string]any{"replicas": 1},
+		},
+		{
+			"empty defaults",
+			nil,
+			map[string]any{"replicas": 2},
+			map[string]any{"replicas": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coalesceHelmValues(tt.defaults, tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coalesceHelmValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findYamlPathLine(t *testing.T) {
+	file := writeTestFile(t, filepath.Join(t.TempDir(), "values.ytt.yaml"), `#@data/values
+---
+image:
+  tag: "1.0"
+args:
+  - first
+  - second
+---
+image:
+  #@overlay/match missing_ok=True
+  pullPolicy: Always
+`)
+
+	tests := []struct {
+		name     string
+		tokens   []string
+		wantLine int
+		wantOk   bool
+	}{
+		{"top-level key", []string{"args"}, 5, true},
+		{"nested key", []string{"image", "tag"}, 4, true},
+		{"list item", []string{"args", "1"}, 7, true},
+		{"last document wins", []string{"image"}, 9, true},
+		{"key in later document", []string{"image", "pullPolicy"}, 11, true},
+		{"root", []string{}, 9, true},
+		{"missing key", []string{"image", "digest"}, 0, false},
+		{"list index out of range", []string{"args", "5"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLine, gotOk := findYamlPathLine(file, tt.tokens)
+			if gotLine != tt.wantLine || gotOk != tt.wantOk {
+				t.Errorf("findYamlPathLine() = (%d, %v), want (%d, %v)", gotLine, gotOk, tt.wantLine, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_locateValueSource(t *testing.T) {
+	dir := t.TempDir()
+	global := writeTestFile(t, filepath.Join(dir, "_global.ytt.yaml"), "replicas: 1\nimage:\n  tag: latest\n")
+	specific := writeTestFile(t, filepath.Join(dir, "chart.yaml"), "service:\n  port: 80\nimage:\n  tag: v1\n")
+	defaults := writeTestFile(t, filepath.Join(dir, "charts", "chart", "values.yaml"), "ingress:\n  enabled: false\n")
+	sources := []string{global, specific}
+
+	tests := []struct {
+		name   string
+		tokens []string
+		want   string
+	}{
+		{"highest precedence file wins", []string{"image", "tag"}, specific + ":4"},
+		{"only in global file", []string{"replicas"}, global + ":1"},
+		{"chart defaults", []string{"ingress", "enabled"}, "chart defaults " + defaults + ":2"},
+		{"missing property falls back to parent", []string{"service", "targetPort"}, specific + ":1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locateValueSource(sources, defaults, tt.tokens); got != tt.want {
+				t.Errorf("locateValueSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateValuesAgainstSchema(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestFile(t, filepath.Join(dir, "values.schema.json"), `{
+  "$schema": "https://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {"repository": {"type": "string"}, "tag": {"type": "string"}}
+    }
+  }
+}`)
+	defaults := writeTestFile(t, filepath.Join(dir, "values.yaml"), "replicas: 1\nimage:\n  repository: nginx\n")
+
+	tests := []struct {
+		name      string
+		values    string
+		wantPaths []string
+	}{
+		{"valid values", "image:\n  tag: \"1.0\"\n", nil},
+		{"wrong type", "replicas: two\n", []string{"/replicas"}},
+		{"default removed", "image:\n  repository: null\n", []string{"/image"}},
+		{"multiple violations", "replicas: 0\nimage:\n  tag: 1\n", []string{"/image/tag", "/replicas"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := writeTestFile(t, filepath.Join(t.TempDir(), "values.yaml"), tt.values)
+			violations, err := validateValuesAgainstSchema(schema, defaults, values)
+			if err != nil {
+				t.Fatalf("validateValuesAgainstSchema() error = %v", err)
+			}
+			var gotPaths []string
+			for _, v := range violations {
+				gotPaths = append(gotPaths, v.Path)
+				if v.Message == "" {
+					t.Errorf("empty message for %s", v.Path)
+				}
+			}
+			if !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("violation paths = %v, want %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestApplication_validateHelmValues_remoteRef(t *testing.T) {
+	g := createGlobe(t)
+	a := &Application{Name: "app", e: &Environment{ID: "env", g: g, cfg: &g.Config}, cfg: &g.Config}
+	chartDir := t.TempDir()
+	writeTestFile(t, filepath.Join(chartDir, "values.schema.json"), `{
+  "type": "object",
+  "properties": {"image": {"$ref": "https://example.com/schemas/image.json"}}
+}`)
+	writeTestFile(t, filepath.Join(chartDir, "values.yaml"), "image: {}\n")
+	values := writeTestFile(t, filepath.Join(t.TempDir(), "values.yaml"), "image:\n  tag: \"1.0\"\n")
+
+	if _, err := validateValuesAgainstSchema(filepath.Join(chartDir, "values.schema.json"), filepath.Join(chartDir, "values.yaml"), values); err == nil {
+		t.Fatal("validateValuesAgainstSchema() expected an error for a remote $ref")
+	}
+	if err := a.validateHelmValues(chartDir, "chart", values); err != nil {
+		t.Errorf("validateHelmValues() error = %v, want the validation to be skipped", err)
+	}
+}
@@ -1,7 +1,6 @@
 package myks
 
 import (
-	"errors"
 	"maps"
 	"path/filepath"
 	"regexp"
@@ -10,9 +9,20 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// DetectChangedEnvsAndApps returns the environments and applications affected by the changed files.
+// Changes are detected with git, or with the manifest of the last render if git is unavailable.
 func (g *Globe) DetectChangedEnvsAndApps(baseRevision string) (EnvAppMap, error) {
 	if !g.WithGit {
-		return nil, errors.New("git is unavailable")
+		if ok, err := isExist(g.changeManifestPath()); err != nil || !ok {
+			return nil, ErrNoChangeManifest
+		}
+		if baseRevision != "" {
+			log.Warn().Str("base-revision", baseRevision).Msg(g.Msg("Git is unavailable, ignoring the base revision"))
+		}
+	}
+
+	if _, err := g.compileSmartModeRules(); err != nil {
+		return nil, err
 	}
 
 	// envAppMap is built later by calling g.runSmartMode
@@ -26,7 +36,13 @@ func (g *Globe) DetectChangedEnvsAndApps(baseRevision string) (EnvAppMap, error)
 		return nil, err
 	}
 
-	changedFiles, err := GetChangedFilesGit(baseRevision)
+	var changedFiles ChangedFiles
+	if g.WithGit {
+		changedFiles, err = GetChangedFilesGit(baseRevision)
+	} else {
+		log.Info().Msg(g.Msg("Git is unavailable, detecting changes since the last render"))
+		changedFiles, err = g.GetChangedFilesManifest()
+	}
 	if err != nil {
 		log.Err(err).Msg(g.Msg("Failed to get diff"))
 		return nil, err
@@ -36,6 +52,10 @@ func (g *Globe) DetectChangedEnvsAndApps(baseRevision string) (EnvAppMap, error)
 	for env, apps := range envAppMap {
 		log.Debug().Str("env", env).Strs("apps", apps).Msg(g.Msg("Detected changes"))
 	}
+	if g.smartModeDeletions, err = g.detectDeletedApplications(changedFiles); err != nil {
+		log.Err(err).Msg(g.Msg("Failed to detect deleted sources"))
+		return nil, err
+	}
 
 	return envAppMap, nil
 }
@@ -59,19 +79,49 @@ func (g *Globe) missingApplications() (EnvAppMap, error) {
 
 func (g *Globe) runSmartMode(changedFiles ChangedFiles) EnvAppMap {
 	regexps := g.buildSmartModeRegexps()
+	explain := newSmartModeExplanation()
+	defer func() { g.smartModeReasons = explain.reasons }()
 
 	envAppMap, err := g.missingApplications()
 	if err != nil {
 		log.Err(err).Msg(g.Msg("Failed to get missing applications"))
 	}
+	for env, apps := range envAppMap {
+		for _, app := range apps {
+			explain.add(env, SmartModeReason{App: app, Rule: smartModeRuleMissing})
+		}
+	}
 
-	changedEnvs, changedPrototypes, globalChange := g.classifyChangedPaths(changedFiles, regexps, envAppMap)
+	// Changes to ytt library files only affect the files loading them
+	changedFiles, globalChange := g.expandLibraryChanges(changedFiles, regexps["global"], explain)
 	if globalChange {
 		return EnvAppMap{g.EnvironmentBaseDir: nil}
 	}
 
-	for env, apps := range g.findPrototypeUsage(changedPrototypes, "") {
-		envAppMap[env] = append(envAppMap[env], apps...)
+	// User-defined rules only apply to files not matched by the built-in library rules
+	rules, err := g.compileSmartModeRules()
+	if err != nil {
+		log.Err(err).Msg(g.Msg("Failed to compile Smart Mode rules"))
+	}
+	for scope, exprs := range rules {
+		regexps[scope] = append(regexps[scope], exprs...)
+	}
+
+	changedEnvs, changedPrototypes, globalChange := g.classifyChangedPaths(changedFiles, regexps, envAppMap, explain)
+	if globalChange {
+		return EnvAppMap{g.EnvironmentBaseDir: nil}
+	}
+
+	for prototype, reasons := range changedPrototypes {
+		for env, apps := range g.findPrototypeUsage([]string{prototype}, "") {
+			envAppMap[env] = append(envAppMap[env], apps...)
+			for _, app := range apps {
+				for _, reason := range reasons {
+					reason.App = app
+					explain.add(env, reason)
+				}
+			}
+		}
 	}
 
 	// If env has changed, all apps in that env are affected
@@ -147,14 +197,16 @@ func (g *Globe) buildSmartModeRegexps() map[string][]*regexp.Regexp {
 }
 
 // classifyChangedPaths categorises each changed file path, mutates envAppMap with
-// env-prototype and app changes, and returns changed env paths, changed prototype names,
-// and whether a global change was detected.
+// env-prototype and app changes, and returns changed env paths, the reasons of changed prototypes
+// keyed by prototype name, and whether a global change was detected.
+// The reasons of all selections are recorded in explain.
 func (g *Globe) classifyChangedPaths(
 	changedFiles ChangedFiles,
 	regexps map[string][]*regexp.Regexp,
 	envAppMap EnvAppMap,
-) (changedEnvs, changedPrototypes []string, globalChange bool) {
-	extractMatches := func(exprs []*regexp.Regexp, path string) []string {
+	explain *smartModeExplanation,
+) (changedEnvs []string, changedPrototypes map[string][]SmartModeReason, globalChange bool) {
+	extractMatches := func(exprs []*regexp.Regexp, path string) ([]string, string) {
 		for _, expr := range exprs {
 			submatches := expr.FindStringSubmatch(path)
 			log.Trace().
@@ -163,42 +215,50 @@ func (g *Globe) classifyChangedPaths(
 				Bool("matched", submatches != nil).
 				Msg(g.Msg("Extracting submatches"))
 			if submatches != nil {
-				return submatches[1:]
+				return submatches[1:], expr.String()
 			}
 		}
-		return nil
+		return nil, ""
 	}
 
+	changedPrototypes = map[string][]SmartModeReason{}
 	for path := range changedFiles {
-		if extractMatches(regexps["global"], path) != nil {
+		if match, pattern := extractMatches(regexps["global"], path); match != nil {
+			explain.add(g.EnvironmentBaseDir, SmartModeReason{File: path, Rule: smartModeRuleGlobal, Pattern: pattern})
 			return nil, nil, true
 		}
 
-		if envMatch := extractMatches(regexps["env"], path); envMatch != nil {
-			changedEnvs = append(changedEnvs, g.AddBaseDirToEnvPath(envMatch[0]))
+		if envMatch, pattern := extractMatches(regexps["env"], path); envMatch != nil {
+			envPath := g.AddBaseDirToEnvPath(envMatch[0])
+			changedEnvs = append(changedEnvs, envPath)
+			explain.add(envPath, SmartModeReason{File: path, Rule: smartModeRuleEnv, Pattern: pattern})
 			continue
 		}
 
-		if protoMatch := extractMatches(regexps["prototype"], path); protoMatch != nil {
-			changedPrototypes = append(changedPrototypes, protoMatch[0])
+		if protoMatch, pattern := extractMatches(regexps["prototype"], path); protoMatch != nil {
+			changedPrototypes[protoMatch[0]] = append(changedPrototypes[protoMatch[0]], SmartModeReason{File: path, Rule: smartModeRulePrototype, Pattern: pattern})
 			continue
 		}
 
-		if envProtoMatch := extractMatches(regexps["env-prototype"], path); envProtoMatch != nil {
+		if envProtoMatch, pattern := extractMatches(regexps["env-prototype"], path); envProtoMatch != nil {
 			envPath := g.AddBaseDirToEnvPath(envProtoMatch[0])
 			for env, apps := range g.findPrototypeUsage([]string{envProtoMatch[1]}, envPath) {
 				envAppMap[env] = append(envAppMap[env], apps...)
+				for _, app := range apps {
+					explain.add(env, SmartModeReason{App: app, File: path, Rule: smartModeRuleEnvPrototype, Pattern: pattern})
+				}
 			}
 			continue
 		}
 
-		if appMatch := extractMatches(regexps["app"], path); appMatch != nil {
+		if appMatch, pattern := extractMatches(regexps["app"], path); appMatch != nil {
 			envPath := g.AddBaseDirToEnvPath(appMatch[0])
 			envAppMap[envPath] = append(envAppMap[envPath], appMatch[1])
+			explain.add(envPath, SmartModeReason{App: appMatch[1], File: path, Rule: smartModeRuleApp, Pattern: pattern})
 			continue
 		}
 
-		if appMatch := extractMatches(regexps["rendered-app"], path); appMatch != nil {
+		if appMatch, pattern := extractMatches(regexps["rendered-app"], path); appMatch != nil {
 			env, err := g.getEnvByID(appMatch[0])
 			if err != nil {
 				log.Err(err).Str("envID", appMatch[0]).Msg(g.Msg("Failed to get environment by ID"))
@@ -206,6 +266,7 @@ func (g *Globe) classifyChangedPaths(
 			}
 			envPath := g.AddBaseDirToEnvPath(env.Dir)
 			envAppMap[envPath] = append(envAppMap[envPath], appMatch[1])
+			explain.add(envPath, SmartModeReason{App: appMatch[1], File: path, Rule: smartModeRuleRenderedApp, Pattern: pattern})
 			continue
 		}
 	}
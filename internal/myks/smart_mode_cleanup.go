@@ -0,0 +1,164 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// detectDeletedApplications finds rendered environments and applications whose sources were deleted:
+// environment directories, applications removed from the environment data, and `_apps/<app>` trees.
+// Returns the application names keyed by environment ID, nil meaning the whole environment.
+// Fails if a changed environment data file is invalid, as the rendered environment would be deleted otherwise.
+func (g *Globe) detectDeletedApplications(changedFiles ChangedFiles) (map[string][]string, error) {
+	e := func(sample string) *regexp.Regexp {
+		return regexp.MustCompile("^" + g.GitPathPrefix + sample + "$")
+	}
+	envDataRe := e("(" + g.EnvironmentBaseDir + ".*)/" + strings.ReplaceAll(regexp.QuoteMeta(g.EnvironmentDataFileName), "\\*", ".*"))
+	appRe := e("(" + g.EnvironmentBaseDir + ".*)/" + g.AppsDir + "/([^/]+)/.*")
+	envRe := e(g.EnvironmentBaseDir + "/.*")
+
+	var affectedEnvs []string
+	checkEnvs := false
+	for path, status := range changedFiles {
+		if submatches := envDataRe.FindStringSubmatch(path); submatches != nil {
+			// Applications may be removed from the environment data, or the environment ID changed
+			affectedEnvs = append(affectedEnvs, submatches[1])
+			checkEnvs = true
+			continue
+		}
+		if !g.isDeletedFile(path, status) {
+			continue
+		}
+		if submatches := appRe.FindStringSubmatch(path); submatches != nil {
+			affectedEnvs = append(affectedEnvs, submatches[1])
+		}
+		if envRe.MatchString(path) {
+			checkEnvs = true
+		}
+	}
+
+	deletions := map[string][]string{}
+	if checkEnvs {
+		knownIDs := map[string]bool{}
+		for _, env := range g.environments {
+			knownIDs[env.ID] = true
+		}
+		for _, dir := range []string{g.RenderedEnvsDir, g.RenderedArgoDir} {
+			entries, err := listDirEntries(filepath.Join(g.RootDir, dir))
+			if err != nil {
+				log.Warn().Err(err).Str("dir", dir).Msg(g.Msg("Unable to list rendered environments"))
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() && !knownIDs[entry.Name()] {
+					deletions[entry.Name()] = nil
+				}
+			}
+		}
+		if len(deletions) > 0 {
+			if err := g.checkEnvData(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, root := range squashEnvPaths(affectedEnvs) {
+		for _, envPath := range g.getEnvironmentsUnderRoot(root) {
+			env := g.environments[envPath]
+			rendered, err := env.renderedApplications()
+			if err != nil {
+				log.Warn().Err(err).Msg(env.Msg("Unable to list rendered applications"))
+				continue
+			}
+			for _, app := range rendered {
+				if _, ok := env.foundApplications[app]; !ok {
+					deletions[env.ID] = append(deletions[env.ID], app)
+				}
+			}
+		}
+	}
+
+	for envID, apps := range deletions {
+		log.Debug().Str("env", envID).Strs("apps", apps).Msg(g.Msg("Detected deleted sources"))
+	}
+	return deletions, nil
+}
+
+// checkEnvData ensures that all environment data files belong to collected environments or to base environments.
+// Environments with invalid data files are not collected, and their rendered manifests would be taken for the ones
+// of a deleted environment.
+func (g *Globe) checkEnvData() error {
+	return filepath.WalkDir(filepath.Join(g.RootDir, g.EnvironmentBaseDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		files, err := filepath.Glob(filepath.Join(path, g.EnvironmentDataFileName))
+		if err != nil || len(files) == 0 {
+			return err
+		}
+		envDir, err := filepath.Rel(g.RootDir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := g.environments[envDir]; ok {
+			return nil
+		}
+		var errs []error
+		for _, file := range files {
+			_, err := NewEnvironment(g, envDir, file)
+			switch {
+			case err == nil:
+				return nil
+			case errors.Is(err, errEnvironmentIDMissing) && len(g.getEnvironmentsUnderRoot(envDir)) > 0:
+				// Data of a base environment
+				return nil
+			case errors.Is(err, errEnvironmentIDMissing):
+				errs = append(errs, fmt.Errorf("environment data file %s has no environment ID", file))
+			default:
+				errs = append(errs, err)
+			}
+		}
+		return fmt.Errorf("invalid environment %s, not removing rendered environments: %w", envDir, errors.Join(errs...))
+	})
+}
+
+// isDeletedFile reports whether a changed file no longer exists, e.g. it was deleted or renamed.
+func (g *Globe) isDeletedFile(path, status string) bool {
+	if status == "D" {
+		return true
+	}
+	ok, err := isExist(filepath.Join(g.RootDir, strings.TrimPrefix(path, g.GitPathPrefix)))
+	return err == nil && !ok
+}
+
+// SmartModeDeletions returns the rendered environments and applications whose sources were deleted,
+// as detected by Smart Mode. Application names are keyed by environment ID, nil meaning the whole environment.
+func (g *Globe) SmartModeDeletions() map[string][]string {
+	return g.smartModeDeletions
+}
+
+// CleanupSmartModeDeletions removes the rendered manifests of deleted environments and applications detected by Smart Mode.
+func (g *Globe) CleanupSmartModeDeletions(dryRun bool) {
+	for _, envID := range slices.Sorted(maps.Keys(g.smartModeDeletions)) {
+		apps := g.smartModeDeletions[envID]
+		renderedEnvDir := filepath.Join(g.RootDir, g.RenderedEnvsDir, envID)
+		renderedArgoDir := filepath.Join(g.RootDir, g.RenderedArgoDir, envID)
+		if apps == nil {
+			g.removeOrLog(renderedEnvDir, dryRun, "rendered environment entry")
+			g.removeOrLog(renderedArgoDir, dryRun, "rendered environment entry")
+			continue
+		}
+		for _, app := range apps {
+			g.removeOrLog(filepath.Join(renderedEnvDir, app), dryRun, "rendered application entry")
+			g.removeOrLog(filepath.Join(renderedArgoDir, getArgoCDAppFileName(app)), dryRun, "rendered application entry")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package myks
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobe_detectDeletedApplications(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{
+		"envs/env1": {
+			Dir:               "envs/env1",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env1",
+			foundApplications: map[string]string{"app1": "app1"},
+		},
+		"envs/env2": {
+			Dir:               "envs/env2",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env2",
+			foundApplications: map[string]string{"app1": "app1"},
+		},
+	}
+	files := []string{
+		filepath.Join(g.RenderedEnvsDir, "env1", "app1", "manifest.yaml"),
+		filepath.Join(g.RenderedEnvsDir, "env1", "app2", "manifest.yaml"),
+		filepath.Join(g.RenderedArgoDir, "env1", "app-app1.yaml"),
+		filepath.Join(g.RenderedArgoDir, "env1", "app-app2.yaml"),
+		filepath.Join(g.RenderedEnvsDir, "env2", "app1", "manifest.yaml"),
+		filepath.Join(g.RenderedEnvsDir, "env2", "app3", "manifest.yaml"),
+		filepath.Join(g.RenderedEnvsDir, "old-env", "app1", "manifest.yaml"),
+		filepath.Join(g.RenderedArgoDir, "old-env", "app-app1.yaml"),
+		"envs/env1/_apps/app1/app-data.ytt.yaml",
+	}
+	for _, file := range files {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, file), []byte("kind: Test")))
+	}
+
+	deletions, err := g.detectDeletedApplications(ChangedFiles{"envs/env1/_apps/app1/app-data.ytt.yaml": "M"})
+	require.NoError(t, err)
+	assert.Empty(t, deletions, "nothing is deleted without deletions or environment data changes")
+
+	g.smartModeDeletions, err = g.detectDeletedApplications(ChangedFiles{
+		"envs/env1/_apps/app2/ytt/app.yaml": "D",
+		"envs/old/env-data.ytt.yaml":        "D",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"env1": {"app2"}, "old-env": nil}, g.SmartModeDeletions(),
+		"env2 is not affected by the changes")
+
+	g.CleanupSmartModeDeletions(true)
+	assert.DirExists(t, filepath.Join(g.RootDir, g.RenderedEnvsDir, "old-env"), "dry run keeps files")
+
+	g.CleanupSmartModeDeletions(false)
+	assert.NoDirExists(t, filepath.Join(g.RootDir, g.RenderedEnvsDir, "old-env"))
+	assert.NoDirExists(t, filepath.Join(g.RootDir, g.RenderedArgoDir, "old-env"))
+	assert.NoDirExists(t, filepath.Join(g.RootDir, g.RenderedEnvsDir, "env1", "app2"))
+	assert.NoFileExists(t, filepath.Join(g.RootDir, g.RenderedArgoDir, "env1", "app-app2.yaml"))
+	assert.DirExists(t, filepath.Join(g.RootDir, g.RenderedEnvsDir, "env1", "app1"))
+	assert.DirExists(t, filepath.Join(g.RootDir, g.RenderedEnvsDir, "env2", "app3"))
+}
+
+func TestGlobe_detectDeletedApplications_invalidEnvData(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{
+		"envs/env1": {Dir: "envs/env1", g: g, cfg: &g.Config, ID: "env1", foundApplications: map[string]string{}},
+	}
+	write := func(file, content string) {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, file), []byte(content)))
+	}
+	write(filepath.Join(g.RenderedEnvsDir, "env1", "app1", "manifest.yaml"), "kind: Test")
+	write(filepath.Join(g.RenderedEnvsDir, "env2", "app1", "manifest.yaml"), "kind: Test")
+	write("envs/env-data.ytt.yaml", "environment: {}")
+	write("envs/env1/env-data.ytt.yaml", "environment: {id: env1}")
+
+	// env2 is not collected due to its invalid data, its rendered manifests must be kept
+	write("envs/env2/env-data.ytt.yaml", "environment: {id: env2")
+	_, err := g.detectDeletedApplications(ChangedFiles{"envs/env2/env-data.ytt.yaml": "M"})
+	require.ErrorContains(t, err, "invalid environment envs/env2")
+
+	write("envs/env2/env-data.ytt.yaml", "environment: {}")
+	_, err = g.detectDeletedApplications(ChangedFiles{"envs/env-data.ytt.yaml": "M"})
+	require.ErrorContains(t, err, "has no environment ID", "all environments are checked, not only the changed ones")
+
+	// The ID of env2 is changed, base environments have no ID
+	write("envs/env2/env-data.ytt.yaml", "environment: {id: env3}")
+	deletions, err := g.detectDeletedApplications(ChangedFiles{"envs/env2/env-data.ytt.yaml": "M"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"env2": nil}, deletions)
+}
@@ -0,0 +1,150 @@
+package myks
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// yttLoadRegexp matches load() statements of files, private libraries ("@lib:") and builtin modules are skipped.
+var yttLoadRegexp = regexp.MustCompile(`load\(\s*["']([^"'@][^"']*)["']`)
+
+// yttLoadIndex maps base names of loaded library files to the files loading them.
+// Paths are relative to the git repository, like the changed files.
+type yttLoadIndex map[string][]string
+
+// isYttLibraryFile reports whether ytt treats the file as a library rather than a template.
+// Library files are only evaluated when loaded, so only their loaders are affected by a change.
+func isYttLibraryFile(filePath string) bool {
+	base := path.Base(filePath)
+	return path.Ext(base) == ".star" || strings.Contains(base, ".lib.")
+}
+
+// buildYttLoadIndex scans ytt files of the global library, prototypes, and environments for load() statements.
+func (g *Globe) buildYttLoadIndex() (yttLoadIndex, error) {
+	index := yttLoadIndex{}
+	for _, dir := range []string{g.YttLibraryDirName, g.PrototypesDir, g.EnvironmentBaseDir} {
+		root := filepath.Join(g.RootDir, dir)
+		err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !isYttSourceFile(d.Name()) {
+				return nil
+			}
+			data, err := os.ReadFile(filePath) // #nosec G304 -- walking the project directories
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(g.RootDir, filePath)
+			if err != nil {
+				return err
+			}
+			loader := g.GitPathPrefix + filepath.ToSlash(rel)
+			for _, match := range yttLoadRegexp.FindAllStringSubmatch(string(data), -1) {
+				base := path.Base(match[1])
+				index[base] = append(index[base], loader)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s for ytt load statements: %w", root, err)
+		}
+	}
+	return index, nil
+}
+
+func isYttSourceFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".star", ".txt":
+		return true
+	}
+	return false
+}
+
+// expandLibraryChanges replaces changed library files matching the global regexps with the files loading them,
+// directly or through other library files. A global change is reported if any other file matches the global
+// regexps, or the load index cannot be built.
+// Loaders are matched by the base name of the loaded file, which may select more applications than necessary.
+func (g *Globe) expandLibraryChanges(changedFiles ChangedFiles, globalRegexps []*regexp.Regexp, explain *smartModeExplanation) (ChangedFiles, bool) {
+	globalPattern := func(filePath string) string {
+		for _, expr := range globalRegexps {
+			if expr.MatchString(filePath) {
+				return expr.String()
+			}
+		}
+		return ""
+	}
+	isGlobal := func(filePath string) bool {
+		return globalPattern(filePath) != ""
+	}
+	globalChange := func(filePath, library string) (ChangedFiles, bool) {
+		explain.add(g.EnvironmentBaseDir, SmartModeReason{File: filePath, Library: library, Rule: smartModeRuleGlobal, Pattern: globalPattern(filePath)})
+		return changedFiles, true
+	}
+
+	expanded := ChangedFiles{}
+	var queue []string
+	for filePath, status := range changedFiles {
+		if !isGlobal(filePath) {
+			expanded[filePath] = status
+			continue
+		}
+		if !isYttLibraryFile(filePath) || strings.Contains(filePath, "/_ytt_lib/") {
+			return globalChange(filePath, "")
+		}
+		queue = append(queue, filePath)
+	}
+	if len(queue) == 0 {
+		return changedFiles, false
+	}
+
+	index, err := g.buildYttLoadIndex()
+	if err != nil {
+		log.Warn().Err(err).Msg(g.Msg("Unable to build the ytt load index, rendering everything"))
+		return globalChange(queue[0], "")
+	}
+
+	// Loaders are attributed to the originally changed library
+	origins := map[string]string{}
+	for _, library := range queue {
+		origins[library] = library
+	}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		library := queue[0]
+		queue = queue[1:]
+		if seen[library] {
+			continue
+		}
+		seen[library] = true
+		loaders := index[path.Base(library)]
+		log.Debug().Str("library", library).Strs("loaders", loaders).Msg(g.Msg("Resolved library dependents"))
+		for _, loader := range loaders {
+			if isGlobal(loader) {
+				if !isYttLibraryFile(loader) {
+					return globalChange(loader, origins[library])
+				}
+				if _, ok := origins[loader]; !ok {
+					origins[loader] = origins[library]
+				}
+				queue = append(queue, loader)
+				continue
+			}
+			if _, ok := expanded[loader]; !ok {
+				expanded[loader] = "M"
+				explain.libraries[loader] = origins[library]
+			}
+		}
+	}
+	return expanded, false
+}
@@ -0,0 +1,92 @@
+package myks
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobe_runSmartMode_libraryLoads(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{
+		"envs/env1": {
+			Dir:               "envs/env1",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env1",
+			foundApplications: map[string]string{"app1": "app1", "app2": "app2"},
+		},
+		"envs/env2": {
+			Dir:               "envs/env2",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env2",
+			foundApplications: map[string]string{"app1": "app1", "app3": "app3"},
+		},
+	}
+	for env, apps := range map[string][]string{"env1": {"app1", "app2"}, "env2": {"app1", "app3"}} {
+		for _, app := range apps {
+			require.NoError(t, createDirectory(filepath.Join(g.RootDir, g.RenderedEnvsDir, env, app)))
+		}
+	}
+	files := map[string]string{
+		"lib/helpers.star":                   "def helper(): return 1",
+		"lib/common.lib.yaml":                `#@ load("helpers.star", "helper")`,
+		"lib/overlay.yaml":                   `#@ load("overlay.star", "overlay")`,
+		"lib/overlay.star":                   "overlay = 1",
+		"prototypes/app1/ytt/all.ytt.yaml":   `#@ load("helpers.star", "helper")`,
+		"envs/env2/_apps/app3/ytt/app.yaml":  `#@ load("@ytt:data", "data")` + "\n" + `#@ load("common.lib.yaml", "common")`,
+		"envs/env1/_apps/app2/ytt/app.yaml":  `#@ load("@ytt:data", "data")`,
+		"prototypes/_vendir/vendir.lib.yaml": "",
+	}
+	for name, content := range files {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, name), []byte(content)))
+	}
+
+	tests := []struct {
+		name         string
+		changedFiles ChangedFiles
+		want         EnvAppMap
+	}{
+		{
+			"library loaded directly and through another library",
+			ChangedFiles{"lib/helpers.star": "M"},
+			EnvAppMap{"envs/env1": {"app1"}, "envs/env2": {"app1", "app3"}},
+		},
+		{
+			"library loaded by an env application",
+			ChangedFiles{"lib/common.lib.yaml": "M"},
+			EnvAppMap{"envs/env2": {"app3"}},
+		},
+		{
+			"unused library",
+			ChangedFiles{"lib/unused.star": "A", "prototypes/_vendir/vendir.lib.yaml": "M"},
+			EnvAppMap{},
+		},
+		{
+			"library loaded by a global template",
+			ChangedFiles{"lib/overlay.star": "M"},
+			EnvAppMap{g.EnvironmentBaseDir: nil},
+		},
+		{
+			"global template",
+			ChangedFiles{"lib/overlay.yaml": "M", "lib/helpers.star": "M"},
+			EnvAppMap{g.EnvironmentBaseDir: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.runSmartMode(tt.changedFiles)
+			for _, apps := range got {
+				sort.Strings(apps)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
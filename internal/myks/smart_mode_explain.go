@@ -0,0 +1,75 @@
+package myks
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Smart Mode selection rules, as reported by the explanation
+const (
+	smartModeRuleGlobal       = "global"
+	smartModeRuleEnv          = "env"
+	smartModeRulePrototype    = "prototype"
+	smartModeRuleEnvPrototype = "env-prototype"
+	smartModeRuleApp          = "app"
+	smartModeRuleRenderedApp  = "rendered-app"
+	smartModeRuleMissing      = "missing"
+)
+
+// SmartModeReason explains why Smart Mode selected an environment or an application.
+type SmartModeReason struct {
+	// Selected application, empty if all applications of the environment are selected
+	App string `json:"app,omitempty"`
+	// Changed file that caused the selection, empty for missing applications
+	File string `json:"file,omitempty"`
+	// Changed library file loaded by File
+	Library string `json:"library,omitempty"`
+	// Rule category: global, env, prototype, env-prototype, app, rendered-app, or missing
+	Rule string `json:"rule"`
+	// Pattern matching File
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// SmartModeReasons maps environment paths to the reasons of their selection.
+type SmartModeReasons map[string][]SmartModeReason
+
+// For returns the reasons of an environment path selected by Smart Mode, including those of nested environments.
+func (r SmartModeReasons) For(envPath string) []SmartModeReason {
+	envPath = filepath.Clean(envPath)
+	var reasons []SmartModeReason
+	for env, envReasons := range r {
+		env = filepath.Clean(env)
+		if env == envPath || strings.HasPrefix(env, envPath+string(filepath.Separator)) {
+			reasons = append(reasons, envReasons...)
+		}
+	}
+	slices.SortFunc(reasons, func(a, b SmartModeReason) int {
+		return cmp.Or(cmp.Compare(a.App, b.App), cmp.Compare(a.File, b.File), cmp.Compare(a.Rule, b.Rule), cmp.Compare(a.Pattern, b.Pattern))
+	})
+	return slices.Compact(reasons)
+}
+
+// smartModeExplanation collects the reasons of a Smart Mode selection.
+type smartModeExplanation struct {
+	reasons SmartModeReasons
+	// libraries maps files loading changed library files to the library files
+	libraries map[string]string
+}
+
+func newSmartModeExplanation() *smartModeExplanation {
+	return &smartModeExplanation{reasons: SmartModeReasons{}, libraries: map[string]string{}}
+}
+
+func (e *smartModeExplanation) add(env string, reason SmartModeReason) {
+	if reason.Library == "" {
+		reason.Library = e.libraries[reason.File]
+	}
+	e.reasons[env] = append(e.reasons[env], reason)
+}
+
+// SmartModeReasons returns the reasons of the last Smart Mode selection.
+func (g *Globe) SmartModeReasons() SmartModeReasons {
+	return g.smartModeReasons
+}
@@ -0,0 +1,52 @@
+package myks
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobe_runSmartMode_reasons(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{
+		"envs/env1": {
+			Dir:               "envs/env1",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env1",
+			foundApplications: map[string]string{"app1": "proto1", "app2": "proto2", "app3": "proto2"},
+		},
+	}
+	for _, app := range []string{"app1", "app2"} {
+		require.NoError(t, createDirectory(filepath.Join(g.RootDir, g.RenderedEnvsDir, "env1", app)))
+	}
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "prototypes/proto1/ytt/main.ytt.yaml"), []byte(`#@ load("helpers.star", "h")`)))
+
+	envAppMap := g.runSmartMode(ChangedFiles{
+		"lib/helpers.star":                      "M",
+		"prototypes/proto2/app-data.ytt.yaml":   "M",
+		"envs/env1/_apps/app2/helm/values.yaml": "M",
+	})
+	assert.ElementsMatch(t, []string{"app1", "app2", "app3"}, envAppMap["envs/env1"])
+
+	reasons := g.SmartModeReasons().For("envs")
+	assert.Equal(t, []SmartModeReason{
+		{App: "app1", File: "prototypes/proto1/ytt/main.ytt.yaml", Library: "lib/helpers.star", Rule: smartModeRulePrototype, Pattern: "^prototypes/(.+)/(?:argocd|helm|static|vendir|ytt-pkg|ytt|lib)/.*$"},
+		{App: "app2", File: "envs/env1/_apps/app2/helm/values.yaml", Rule: smartModeRuleApp, Pattern: "^(envs.*)/_apps/([^/]+)/(?:argocd|helm|static|vendir|ytt-pkg|ytt|lib)/.*$"},
+		{App: "app2", File: "prototypes/proto2/app-data.ytt.yaml", Rule: smartModeRulePrototype, Pattern: `^prototypes/(.+)/app-data.*\.yaml$`},
+		{App: "app3", Rule: smartModeRuleMissing},
+		{App: "app3", File: "prototypes/proto2/app-data.ytt.yaml", Rule: smartModeRulePrototype, Pattern: `^prototypes/(.+)/app-data.*\.yaml$`},
+	}, reasons)
+
+	envAppMap = g.runSmartMode(ChangedFiles{"lib/overlay.ytt.yaml": "M"})
+	assert.Equal(t, EnvAppMap{g.EnvironmentBaseDir: nil}, envAppMap)
+	assert.Equal(t, []SmartModeReason{
+		{File: "lib/overlay.ytt.yaml", Rule: smartModeRuleGlobal, Pattern: "^lib/.*$"},
+		{App: "app3", Rule: smartModeRuleMissing},
+	}, g.SmartModeReasons().For(g.EnvironmentBaseDir))
+}
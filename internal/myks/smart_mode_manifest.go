@@ -0,0 +1,224 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+const changeManifestFileName = "smart-mode-manifest.yaml"
+
+// ErrNoChangeManifest is returned when changes cannot be detected without git, as nothing was rendered yet.
+var ErrNoChangeManifest = errors.New("git is unavailable and no manifest of a previous render exists")
+
+// changeManifest holds the hashes of the input files of the last successful render.
+type changeManifest struct {
+	Files map[string]changeManifestFile `json:"files"`
+}
+
+// changeManifestFile is a hashed input file. The size and modification time are used to skip hashing unchanged files.
+type changeManifestFile struct {
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+func (g *Globe) changeManifestPath() string {
+	return filepath.Join(g.RootDir, g.ServiceDirName, changeManifestFileName)
+}
+
+// hashInputFiles hashes all files of the project, keyed by their slash-separated path relative to the root directory.
+// Hidden files and directories, the service directory, and rendered manifests are skipped.
+// Hashes of files with the same size and modification time as in the previous manifest are reused.
+func (g *Globe) hashInputFiles(previous map[string]changeManifestFile) (map[string]changeManifestFile, error) {
+	files := map[string]changeManifestFile{}
+	err := filepath.WalkDir(g.RootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(g.RootDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if g.isIgnoredInputPath(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		file, err := hashInputFile(path, previous[key])
+		if err != nil {
+			return err
+		}
+		files[key] = file
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hashing input files: %w", err)
+	}
+	return files, nil
+}
+
+// hashInputFile hashes a file, unless its size and modification time match the previous entry.
+func hashInputFile(path string, previous changeManifestFile) (changeManifestFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return changeManifestFile{}, err
+	}
+	if previous.Hash != "" && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+		return previous, nil
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return changeManifestFile{}, err
+	}
+	return changeManifestFile{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// isIgnoredInputPath reports whether a path relative to the root directory is not a rendering input:
+// hidden files and directories, the service directory, or rendered manifests.
+func (g *Globe) isIgnoredInputPath(rel string) bool {
+	for _, dir := range []string{g.ServiceDirName, g.RenderedEnvsDir, g.RenderedArgoDir} {
+		dir = filepath.Clean(dir)
+		if rel == dir || strings.HasPrefix(rel, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	for part := range strings.SplitSeq(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveChangeManifest records the hashes of the input files, so that Smart Mode can detect changes without git.
+// It is called without git after a successful render of all applications or of the applications selected by Smart Mode.
+// After a Smart Mode render, the files compared during the detection are recorded, so changes made
+// during the render are detected by the next run.
+func (g *Globe) SaveChangeManifest() error {
+	files := g.changeManifestFiles
+	if files == nil {
+		previous, err := g.readChangeManifest()
+		if err != nil {
+			return err
+		}
+		if files, err = g.hashInputFiles(previous.Files); err != nil {
+			return err
+		}
+	}
+	return g.writeChangeManifest(files)
+}
+
+// UpdateChangeManifest records the hashes of the changed files only, keeping the recorded hashes of other files.
+// A removed path also removes the files below it.
+func (g *Globe) UpdateChangeManifest(changedFiles ChangedFiles) error {
+	manifest, err := g.readChangeManifest()
+	if err != nil {
+		return err
+	}
+	if manifest.Files == nil {
+		// Without a previous manifest, unchanged files are unknown
+		return g.SaveChangeManifest()
+	}
+	for path := range changedFiles {
+		key := strings.TrimPrefix(path, g.GitPathPrefix)
+		rel := filepath.FromSlash(key)
+		if g.isIgnoredInputPath(rel) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(g.RootDir, rel))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			for recorded := range manifest.Files {
+				if recorded == key || strings.HasPrefix(recorded, key+"/") {
+					delete(manifest.Files, recorded)
+				}
+			}
+		case err != nil:
+			return fmt.Errorf("hashing input files: %w", err)
+		case info.Mode().IsRegular():
+			file, err := hashInputFile(filepath.Join(g.RootDir, rel), manifest.Files[key])
+			if err != nil {
+				return fmt.Errorf("hashing input files: %w", err)
+			}
+			manifest.Files[key] = file
+		}
+	}
+	return g.writeChangeManifest(manifest.Files)
+}
+
+func (g *Globe) readChangeManifest() (changeManifest, error) {
+	var manifest changeManifest
+	data, err := os.ReadFile(g.changeManifestPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, fmt.Errorf("reading change manifest: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing change manifest %s: %w", g.changeManifestPath(), err)
+	}
+	return manifest, nil
+}
+
+func (g *Globe) writeChangeManifest(files map[string]changeManifestFile) error {
+	data, err := yaml.Marshal(changeManifest{Files: files})
+	if err != nil {
+		return fmt.Errorf("marshaling change manifest: %w", err)
+	}
+	return writeFile(g.changeManifestPath(), data)
+}
+
+// GetChangedFilesManifest returns the files changed since the manifest of the last render was saved.
+func (g *Globe) GetChangedFilesManifest() (ChangedFiles, error) {
+	if ok, err := isExist(g.changeManifestPath()); err != nil {
+		return nil, fmt.Errorf("reading change manifest: %w", err)
+	} else if !ok {
+		return nil, ErrNoChangeManifest
+	}
+	manifest, err := g.readChangeManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := g.hashInputFiles(manifest.Files)
+	if err != nil {
+		return nil, err
+	}
+	g.changeManifestFiles = current
+	return diffChangeManifest(manifest.Files, current), nil
+}
+
+// diffChangeManifest compares two sets of file hashes and returns the changes in git status notation.
+func diffChangeManifest(previous, current map[string]changeManifestFile) ChangedFiles {
+	files := ChangedFiles{}
+	for path, file := range current {
+		if previousFile, ok := previous[path]; !ok {
+			files[path] = "A"
+		} else if previousFile.Hash != file.Hash {
+			files[path] = "M"
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			files[path] = "D"
+		}
+	}
+	log.Trace().Interface("changedFiles", files).Msg("Detected changes with the change manifest")
+	return files
+}
@@ -0,0 +1,107 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobe_changeManifest(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	files := map[string]string{
+		"envs/env1/env-data.ytt.yaml":           "env",
+		"envs/env1/_apps/app1/ytt/app.yaml":     "app1",
+		"envs/env1/_apps/app2/ytt/app.yaml":     "app2",
+		"prototypes/app1/app-data.ytt.yaml":     "proto",
+		".git/HEAD":                             "ignored",
+		".myks/envs/env1/vendor/file.yaml":      "ignored",
+		"rendered/envs/env1/app1/manifest.yaml": "ignored",
+	}
+	for name, content := range files {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, name), []byte(content)))
+	}
+
+	_, err := g.GetChangedFilesManifest()
+	require.ErrorIs(t, err, ErrNoChangeManifest)
+	_, err = g.DetectChangedEnvsAndApps("")
+	require.ErrorIs(t, err, ErrNoChangeManifest, "without git, a manifest is required")
+
+	require.NoError(t, g.SaveChangeManifest())
+	changed, err := g.GetChangedFilesManifest()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "envs/env1/_apps/app1/ytt/app.yaml"), []byte("changed")))
+	require.NoError(t, os.RemoveAll(filepath.Join(g.RootDir, "envs/env1/_apps/app2")))
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "prototypes/app1/ytt/new.yaml"), []byte("new")))
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "rendered/envs/env1/app1/manifest.yaml"), []byte("changed")))
+
+	changed, err = g.GetChangedFilesManifest()
+	require.NoError(t, err)
+	assert.Equal(t, ChangedFiles{
+		"envs/env1/_apps/app1/ytt/app.yaml": "M",
+		"envs/env1/_apps/app2/ytt/app.yaml": "D",
+		"prototypes/app1/ytt/new.yaml":      "A",
+	}, changed)
+}
+
+func TestGlobe_SaveChangeManifest_keepsChangesDuringRender(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	for _, name := range []string{"envs/env1/_apps/app1/ytt/app.yaml", "envs/env1/_apps/app2/ytt/app.yaml"} {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, name), []byte("initial")))
+	}
+	require.NoError(t, g.SaveChangeManifest())
+
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "envs/env1/_apps/app1/ytt/app.yaml"), []byte("detected")))
+	run := NewWithDefaults()
+	run.RootDir = g.RootDir
+	changed, err := run.GetChangedFilesManifest()
+	require.NoError(t, err)
+	assert.Equal(t, ChangedFiles{"envs/env1/_apps/app1/ytt/app.yaml": "M"}, changed)
+
+	// Changed while the selection is rendered
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "envs/env1/_apps/app2/ytt/app.yaml"), []byte("during render")))
+	require.NoError(t, run.SaveChangeManifest())
+
+	next := NewWithDefaults()
+	next.RootDir = g.RootDir
+	changed, err = next.GetChangedFilesManifest()
+	require.NoError(t, err)
+	assert.Equal(t, ChangedFiles{"envs/env1/_apps/app2/ytt/app.yaml": "M"}, changed)
+}
+
+func TestGlobe_UpdateChangeManifest(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	for _, name := range []string{
+		"envs/env1/_apps/app1/ytt/app.yaml",
+		"envs/env1/_apps/app2/ytt/app.yaml",
+		"envs/env1/_apps/app3/ytt/app.yaml",
+	} {
+		require.NoError(t, writeFile(filepath.Join(g.RootDir, name), []byte("initial")))
+	}
+	require.NoError(t, g.SaveChangeManifest())
+
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "envs/env1/_apps/app1/ytt/app.yaml"), []byte("rendered")))
+	require.NoError(t, writeFile(filepath.Join(g.RootDir, "envs/env1/_apps/app2/ytt/app.yaml"), []byte("not rendered")))
+	require.NoError(t, os.RemoveAll(filepath.Join(g.RootDir, "envs/env1/_apps/app3")))
+	require.NoError(t, g.UpdateChangeManifest(ChangedFiles{
+		"envs/env1/_apps/app1/ytt/app.yaml": "M",
+		"envs/env1/_apps/app3":              "D",
+	}))
+
+	changed, err := g.GetChangedFilesManifest()
+	require.NoError(t, err)
+	assert.Equal(t, ChangedFiles{"envs/env1/_apps/app2/ytt/app.yaml": "M"}, changed, "only rendered changes are recorded")
+}
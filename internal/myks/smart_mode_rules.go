@@ -0,0 +1,104 @@
+package myks
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Smart Mode rule scopes
+const (
+	SmartModeScopeGlobal    = "global"
+	SmartModeScopeEnv       = "env"
+	SmartModeScopePrototype = "prototype"
+	SmartModeScopeApp       = "app"
+)
+
+// smartModeRulePlaceholders maps placeholders of rule patterns to their capturing groups.
+// Paths are matched lazily, so the shortest path followed by the rest of the pattern is captured.
+var smartModeRulePlaceholders = map[string]string{
+	"{env}":       "(.+?)",
+	"{prototype}": "(.+?)",
+	"{app}":       "([^/]+)",
+}
+
+// smartModeScopePlaceholders lists the placeholders each scope requires, in the order of the submatches.
+var smartModeScopePlaceholders = map[string][]string{
+	SmartModeScopeGlobal:    nil,
+	SmartModeScopeEnv:       {"{env}"},
+	SmartModeScopePrototype: {"{prototype}"},
+	SmartModeScopeApp:       {"{env}", "{app}"},
+}
+
+// SmartModeRule selects the scope of processing for changed files matching a glob pattern.
+// Patterns are relative to the root directory and may capture the environment path with {env},
+// the prototype name with {prototype}, and the application name with {app}.
+type SmartModeRule struct {
+	Pattern string `mapstructure:"pattern"`
+	Scope   string `mapstructure:"scope"`
+}
+
+// compile converts the rule pattern into a regexp with the submatches expected for its scope.
+func (r SmartModeRule) compile(pathPrefix string) (*regexp.Regexp, error) {
+	required, ok := smartModeScopePlaceholders[r.Scope]
+	if !ok {
+		return nil, fmt.Errorf("smart mode rule %q: unknown scope %q", r.Pattern, r.Scope)
+	}
+
+	var found []string
+	var expr strings.Builder
+	for rest := r.Pattern; rest != ""; {
+		if placeholder := smartModeRulePlaceholder(rest); placeholder != "" {
+			found = append(found, placeholder)
+			expr.WriteString(smartModeRulePlaceholders[placeholder])
+			rest = rest[len(placeholder):]
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rest, "**/"):
+			// Zero or more directories, so a/**/b matches a/b as well
+			expr.WriteString("(?:.*/)?")
+			rest = rest[3:]
+		case strings.HasPrefix(rest, "**"):
+			expr.WriteString(".*")
+			rest = rest[2:]
+		case rest[0] == '*':
+			expr.WriteString("[^/]*")
+			rest = rest[1:]
+		case rest[0] == '?':
+			expr.WriteString("[^/]")
+			rest = rest[1:]
+		default:
+			expr.WriteString(regexp.QuoteMeta(rest[:1]))
+			rest = rest[1:]
+		}
+	}
+
+	if !slices.Equal(found, required) {
+		return nil, fmt.Errorf("smart mode rule %q: scope %q requires the placeholders %v in this order", r.Pattern, r.Scope, required)
+	}
+	return regexp.Compile("^" + regexp.QuoteMeta(pathPrefix) + expr.String() + "$")
+}
+
+func smartModeRulePlaceholder(pattern string) string {
+	for placeholder := range smartModeRulePlaceholders {
+		if strings.HasPrefix(pattern, placeholder) {
+			return placeholder
+		}
+	}
+	return ""
+}
+
+// compileSmartModeRules compiles the user-defined Smart Mode rules, keyed by scope.
+func (g *Globe) compileSmartModeRules() (map[string][]*regexp.Regexp, error) {
+	regexps := map[string][]*regexp.Regexp{}
+	for _, rule := range g.SmartModeRules {
+		expr, err := rule.compile(g.GitPathPrefix)
+		if err != nil {
+			return nil, err
+		}
+		regexps[rule.Scope] = append(regexps[rule.Scope], expr)
+	}
+	return regexps, nil
+}
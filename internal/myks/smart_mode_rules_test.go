@@ -0,0 +1,107 @@
+package myks
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmartModeRule_compile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rule    SmartModeRule
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{SmartModeRule{"scripts/**", SmartModeScopeGlobal}, "scripts/gen/data.sh", []string{}, false},
+		{SmartModeRule{"scripts/*.sh", SmartModeScopeGlobal}, "scripts/gen/data.sh", nil, false},
+		{SmartModeRule{"charts/**/values.yaml", SmartModeScopeGlobal}, "charts/values.yaml", []string{}, false},
+		{SmartModeRule{"charts/**/values.yaml", SmartModeScopeGlobal}, "charts/a/b/values.yaml", []string{}, false},
+		{SmartModeRule{"charts/**/values.yaml", SmartModeScopeGlobal}, "charts/my-values.yaml", nil, false},
+		{SmartModeRule{"{env}/_apps/{app}/**/*.json", SmartModeScopeApp}, "envs/dev/_apps/app1/x.json", []string{"envs/dev", "app1"}, false},
+		{SmartModeRule{"envs/{env}/_env/static/**", SmartModeScopeEnv}, "envs/group/dev/_env/static/a/b.yaml", []string{"group/dev"}, false},
+		{SmartModeRule{"config/{prototype}/**", SmartModeScopePrototype}, "config/app1/values/a.yaml", []string{"app1"}, false},
+		{SmartModeRule{"{env}/_apps/{app}/custom/*", SmartModeScopeApp}, "envs/dev/_apps/app1/custom/x.json", []string{"envs/dev", "app1"}, false},
+		{SmartModeRule{"{app}/{env}/**", SmartModeScopeApp}, "", nil, true},
+		{SmartModeRule{"envs/**", SmartModeScopeEnv}, "", nil, true},
+		{SmartModeRule{"envs/{env}/**", "cluster"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule.Pattern, func(t *testing.T) {
+			t.Parallel()
+			expr, err := tt.rule.compile("")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			submatches := expr.FindStringSubmatch(tt.path)
+			if tt.want == nil {
+				assert.Nil(t, submatches)
+				return
+			}
+			require.NotNil(t, submatches)
+			assert.Equal(t, tt.want, submatches[1:])
+		})
+	}
+}
+
+func TestGlobe_runSmartMode_rules(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	g.environments = map[string]*Environment{
+		"envs/env1": {
+			Dir:               "envs/env1",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env1",
+			foundApplications: map[string]string{"app1": "app1", "app2": "app2"},
+		},
+		"envs/env2": {
+			Dir:               "envs/env2",
+			g:                 g,
+			cfg:               &g.Config,
+			ID:                "env2",
+			foundApplications: map[string]string{"app2": "app2"},
+		},
+	}
+	for env, apps := range map[string][]string{"env1": {"app1", "app2"}, "env2": {"app2"}} {
+		for _, app := range apps {
+			require.NoError(t, createDirectory(filepath.Join(g.RootDir, g.RenderedEnvsDir, env, app)))
+		}
+	}
+	g.SmartModeRules = []SmartModeRule{
+		{Pattern: "scripts/**", Scope: SmartModeScopeGlobal},
+		{Pattern: "envs/{env}/_env/static/**", Scope: SmartModeScopeEnv},
+		{Pattern: "charts-values/{prototype}/*.yaml", Scope: SmartModeScopePrototype},
+		{Pattern: "{env}/_apps/{app}/custom/**", Scope: SmartModeScopeApp},
+	}
+
+	tests := []struct {
+		name         string
+		changedFiles ChangedFiles
+		want         EnvAppMap
+	}{
+		{"global", ChangedFiles{"scripts/generate.sh": "M"}, EnvAppMap{g.EnvironmentBaseDir: nil}},
+		{"env", ChangedFiles{"envs/env2/_env/static/file.txt": "M"}, EnvAppMap{"envs/env2": nil}},
+		{"prototype", ChangedFiles{"charts-values/app2/values.yaml": "M"}, EnvAppMap{"envs/env1": {"app2"}, "envs/env2": {"app2"}}},
+		{"app", ChangedFiles{"envs/env1/_apps/app1/custom/data.json": "M"}, EnvAppMap{"envs/env1": {"app1"}}},
+		{"unmatched", ChangedFiles{"docs/README.md": "M"}, EnvAppMap{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.runSmartMode(tt.changedFiles)
+			for _, apps := range got {
+				sort.Strings(apps)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
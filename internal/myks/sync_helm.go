@@ -22,6 +22,10 @@ type HelmSyncer struct {
 	// Used to ensure each chart's dependencies are built at most once per run.
 	builtCharts sync.Map
 
+	// Offline skips dependency builds, which need network access.
+	// Dependencies of cached charts were built when the cache entry was synced.
+	Offline bool
+
 	// Dedup counters for observability
 	BuildExecuted atomic.Int64
 	BuildSkipped  atomic.Int64
@@ -50,7 +54,7 @@ func (hr *HelmSyncer) Sync(a *Application, _ string) error {
 		return err
 	}
 
-	chartsDirs, err := a.getHelmChartsDirs(hr.getStepName())
+	charts, err := a.getHelmCharts(hr.getStepName(), &helmConfig)
 	if err != nil {
 		return err
 	}
@@ -61,16 +65,19 @@ func (hr *HelmSyncer) Sync(a *Application, _ string) error {
 	}
 
 	chartNames := []string{}
-	for _, chartDir := range chartsDirs {
-		chartName := filepath.Base(chartDir)
-		chartNames = append(chartNames, chartName)
-		chartConfig := helmConfig.getChartConfig(chartName)
+	for _, chart := range charts {
+		chartNames = append(chartNames, chart.Name)
+		chartConfig := helmConfig.getChartConfig(chart.Name)
 		if !chartConfig.BuildDependencies {
-			log.Debug().Msg(a.Msg(hr.getStepName(), fmt.Sprintf(".helm.charts[%s].buildDependencies is disabled, skipping", chartName)))
+			log.Debug().Msg(a.Msg(hr.getStepName(), fmt.Sprintf(".helm.charts[%s].buildDependencies is disabled, skipping", chart.Name)))
+			continue
+		}
+		if hr.Offline {
+			log.Debug().Msg(a.Msg(hr.getStepName(), fmt.Sprintf("Offline mode, skipping dependencies build of %s", chart.Name)))
 			continue
 		}
-		cacheName := findCacheNameForChart(linksMap, chartDir, a.cfg.HelmChartsDirName)
-		if err := hr.buildChartOnce(a, cacheName, chartDir); err != nil {
+		cacheName, subPath := findCacheNameForChart(linksMap, chart.VendorPath)
+		if err := hr.buildChartInCacheOnce(a, cacheName, subPath, chart.Dir); err != nil {
 			return err
 		}
 	}
@@ -83,26 +90,28 @@ func (hr *HelmSyncer) Sync(a *Application, _ string) error {
 	return nil
 }
 
-// findCacheNameForChart looks up the vendir cache name for a given chart directory by
-// searching the links map. The chart directory base name (e.g. "nginx") combined with
-// the helm charts dir name (e.g. "charts") forms the vendor path key (e.g. "charts/nginx").
-func findCacheNameForChart(linksMap map[string]string, chartDir, helmChartsDirName string) string {
-	vendorPath := filepath.Join(helmChartsDirName, filepath.Base(chartDir))
-	return linksMap[vendorPath]
-}
-
 // buildChartOnce ensures helm dependencies are built at most once per chart cache entry per run.
 // The first goroutine per cacheName acquires the write lock and runs helm dependencies build;
 // subsequent goroutines wait for that result and skip the redundant build.
 // When cacheName is empty (chart not in the links map), falls back to a per-chart-dir key.
 // This deduplicates concurrent builds of the same chart directory during the current run.
 func (hr *HelmSyncer) buildChartOnce(a *Application, cacheName, chartDir string) error {
-	key := cacheName
+	return hr.buildChartInCacheOnce(a, cacheName, "", chartDir)
+}
+
+// buildChartInCacheOnce is like buildChartOnce for a chart located in a sub-path of a cache entry,
+// e.g. a chart in a vendored git repository. Builds are deduplicated per cache name and sub-path,
+// while the lock is taken on the cache name, the same as during rendering.
+func (hr *HelmSyncer) buildChartInCacheOnce(a *Application, cacheName, subPath, chartDir string) error {
+	key, lockName := cacheName, cacheName
+	if cacheName != "" && subPath != "" {
+		key = cacheName + ":" + filepath.ToSlash(subPath)
+	}
 	if key == "" {
 		// Fallback for charts not tracked via vendir cache (e.g. checked-in charts).
 		// Use the chart dir path as the dedup key so concurrent builds of the same dir are deduplicated.
 		log.Debug().Str("chart", filepath.Base(chartDir)).Msg(a.Msg(hr.getStepName(), "Chart not found in links map, using chart dir as dedup key"))
-		key = chartDir
+		key, lockName = chartDir, chartDir
 	}
 
 	result := &syncResult{done: make(chan struct{})}
@@ -123,11 +132,18 @@ func (hr *HelmSyncer) buildChartOnce(a *Application, cacheName, chartDir string)
 	// The write lock is taken on the dedup/lock key (`key`), which is the cache name when available
 	// and otherwise falls back to the chart directory. This coordinates with any render-phase read lock
 	// taken on the same key, preventing reads of partially-built chart data during helm dependency download.
-	unlock := hr.locker.LockNames(slices.Values([]string{key}), true)
+	unlock := hr.locker.LockNames(slices.Values([]string{lockName}), true)
 	defer unlock()
 
 	hr.BuildExecuted.Add(1)
 	result.err = hr.helmBuild(a, chartDir)
+	if result.err == nil && cacheName != "" {
+		// Built dependencies are part of the cache entry data. The digest is only used for verification,
+		// so a failure to record it doesn't fail the build.
+		if err := writeCacheDigest(a, cacheName); err != nil {
+			log.Warn().Err(err).Str("cache", cacheName).Msg(a.Msg(hr.getStepName(), "Unable to record cache entry digest"))
+		}
+	}
 	return result.err
 }
 
@@ -0,0 +1,82 @@
+package myks
+
+import (
+	"strings"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+)
+
+// SourceMirror rewrites source URLs starting with Match to start with Replace instead.
+type SourceMirror struct {
+	Match   string `mapstructure:"match"`
+	Replace string `mapstructure:"replace"`
+}
+
+// SourceMirrors is an ordered list of mirrors, the first matching mirror is applied.
+type SourceMirrors []SourceMirror
+
+// rewrite returns the URL with the first matching mirror applied.
+func (m SourceMirrors) rewrite(url string) string {
+	for _, mirror := range m {
+		if mirror.Match != "" && strings.HasPrefix(url, mirror.Match) {
+			return mirror.Replace + strings.TrimPrefix(url, mirror.Match)
+		}
+	}
+	return url
+}
+
+// restore reverts the rewrite of a URL, e.g. of an image resolved from a mirror.
+func (m SourceMirrors) restore(url string) string {
+	for _, mirror := range m {
+		if mirror.Replace != "" && strings.HasPrefix(url, mirror.Replace) {
+			return mirror.Match + strings.TrimPrefix(url, mirror.Replace)
+		}
+	}
+	return url
+}
+
+// apply rewrites helm repository URLs, git remotes, and OCI image references of a vendir content.
+func (m SourceMirrors) apply(content vendirconf.DirectoryContents) vendirconf.DirectoryContents { //nolint:gocritic // external type
+	if len(m) == 0 {
+		return content
+	}
+	switch {
+	case content.HelmChart != nil && content.HelmChart.Repository != nil:
+		chart := *content.HelmChart
+		repository := *chart.Repository
+		repository.URL = m.rewrite(repository.URL)
+		chart.Repository = &repository
+		content.HelmChart = &chart
+	case content.Git != nil:
+		git := *content.Git
+		git.URL = m.rewrite(git.URL)
+		content.Git = &git
+	case content.Image != nil:
+		image := *content.Image
+		image.URL = m.rewrite(image.URL)
+		content.Image = &image
+	case content.ImgpkgBundle != nil:
+		bundle := *content.ImgpkgBundle
+		bundle.Image = m.rewrite(bundle.Image)
+		content.ImgpkgBundle = &bundle
+	}
+	return content
+}
+
+// restoreLock reverts mirrored image references in a vendir lock, so lock files keep the upstream sources.
+func (m SourceMirrors) restoreLock(lock vendirconf.LockDirectoryContents) vendirconf.LockDirectoryContents { //nolint:gocritic // external type
+	if len(m) == 0 {
+		return lock
+	}
+	if lock.Image != nil {
+		image := *lock.Image
+		image.URL = m.restore(image.URL)
+		lock.Image = &image
+	}
+	if lock.ImgpkgBundle != nil {
+		bundle := *lock.ImgpkgBundle
+		bundle.Image = m.restore(bundle.Image)
+		lock.ImgpkgBundle = &bundle
+	}
+	return lock
+}
@@ -0,0 +1,99 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+func TestSourceMirrors_rewrite(t *testing.T) {
+	t.Parallel()
+
+	mirrors := SourceMirrors{
+		{Match: "https://github.com/", Replace: "https://git.mirror.local/github/"},
+		{Match: "ghcr.io/", Replace: "registry.mirror.local/ghcr/"},
+		{Match: "ghcr.io/org/", Replace: "never.applied/"},
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/org/repo", "https://git.mirror.local/github/org/repo"},
+		{"ghcr.io/org/image:v1", "registry.mirror.local/ghcr/org/image:v1"},
+		{"https://charts.example.com", "https://charts.example.com"},
+	}
+	for _, tt := range tests {
+		got := mirrors.rewrite(tt.url)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.url, mirrors.restore(got))
+	}
+}
+
+func TestSourceMirrors_apply(t *testing.T) {
+	t.Parallel()
+
+	mirrors := SourceMirrors{
+		{Match: "https://charts.example.com", Replace: "https://charts.mirror.local"},
+		{Match: "https://github.com/", Replace: "https://git.mirror.local/"},
+		{Match: "ghcr.io/", Replace: "registry.mirror.local/"},
+	}
+	helm := vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{
+		Name:       "chart",
+		Repository: &vendirconf.DirectoryContentsHelmChartRepo{URL: "https://charts.example.com"},
+	}}
+	got := mirrors.apply(helm)
+	assert.Equal(t, "https://charts.mirror.local", got.HelmChart.Repository.URL)
+	assert.Equal(t, "https://charts.example.com", helm.HelmChart.Repository.URL, "the original content is not modified")
+
+	got = mirrors.apply(vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{URL: "https://github.com/org/repo"}})
+	assert.Equal(t, "https://git.mirror.local/org/repo", got.Git.URL)
+
+	got = mirrors.apply(vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "ghcr.io/org/image:v1"}})
+	assert.Equal(t, "registry.mirror.local/org/image:v1", got.Image.URL)
+
+	got = mirrors.apply(vendirconf.DirectoryContents{ImgpkgBundle: &vendirconf.DirectoryContentsImgpkgBundle{Image: "ghcr.io/org/bundle:v1"}})
+	assert.Equal(t, "registry.mirror.local/org/bundle:v1", got.ImgpkgBundle.Image)
+
+	lock := mirrors.restoreLock(vendirconf.LockDirectoryContents{
+		Image: &vendirconf.LockDirectoryContentsImage{URL: "registry.mirror.local/org/image@sha256:abcd"},
+	})
+	assert.Equal(t, "ghcr.io/org/image@sha256:abcd", lock.Image.URL)
+}
+
+func TestVendirSyncer_extractCacheItemsWithMirrors(t *testing.T) {
+	t.Parallel()
+
+	app := newLockTestApp(t)
+	vendirConfig := `apiVersion: vendir.k14s.io/v1alpha1
+kind: Config
+directories:
+  - path: ytt
+    contents:
+      - path: repo
+        git:
+          url: https://github.com/org/repo
+          ref: main
+`
+	require.NoError(t, writeFile(app.expandServicePath(app.cfg.VendirConfigFileName), []byte(vendirConfig)))
+
+	upstream, err := NewVendirSyncer(locker.NewLocker()).extractCacheItems(app, nil)
+	require.NoError(t, err)
+
+	v := NewVendirSyncer(locker.NewLocker())
+	v.Mirrors = SourceMirrors{{Match: "https://github.com/", Replace: "https://git.mirror.local/"}}
+	mirrored, err := v.extractCacheItems(app, nil)
+	require.NoError(t, err)
+	assert.Equal(t, upstream, mirrored, "cache names refer to the upstream sources")
+
+	cacheName := mirrored[filepath.Join("ytt", "repo")]
+	cacheConfig, err := os.ReadFile(filepath.Join(app.expandVendirCache(cacheName), app.cfg.VendirConfigFileName))
+	require.NoError(t, err)
+	assert.Contains(t, string(cacheConfig), "https://git.mirror.local/org/repo")
+	assert.NotContains(t, string(cacheConfig), "github.com")
+}
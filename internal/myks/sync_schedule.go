@@ -0,0 +1,159 @@
+package myks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// SyncSchedule controls retries, timeouts, and concurrency of vendir syncs.
+type SyncSchedule struct {
+	// Number of retries of a failed sync
+	Retries int `mapstructure:"retries"`
+	// Delay before the first retry, doubled for every following retry
+	Backoff time.Duration `default:"2s" mapstructure:"backoff"`
+	// Maximum duration of a single sync attempt, 0 means no timeout
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Maximum number of concurrent syncs, 0 means no limit
+	Concurrency int `mapstructure:"concurrency"`
+	// Maximum number of concurrent syncs per source host, 0 means no limit
+	PerHostConcurrency int `mapstructure:"per-host-concurrency"`
+}
+
+// syncScheduler limits the number of concurrent syncs, in total and per source host.
+type syncScheduler struct {
+	total   chan struct{}
+	perHost int
+	mu      sync.Mutex
+	hosts   map[string]chan struct{}
+}
+
+func newSyncScheduler(s SyncSchedule) *syncScheduler {
+	scheduler := &syncScheduler{perHost: s.PerHostConcurrency, hosts: map[string]chan struct{}{}}
+	if s.Concurrency > 0 {
+		scheduler.total = make(chan struct{}, s.Concurrency)
+	}
+	return scheduler
+}
+
+// acquire blocks until a sync slot for the host is available and returns a function releasing it.
+func (s *syncScheduler) acquire(host string) func() {
+	hostSlots := s.hostSlots(host)
+	// The host slot is taken first, so syncs waiting for a busy host do not hold a total slot
+	if hostSlots != nil {
+		hostSlots <- struct{}{}
+	}
+	if s.total != nil {
+		s.total <- struct{}{}
+	}
+	return func() {
+		if s.total != nil {
+			<-s.total
+		}
+		if hostSlots != nil {
+			<-hostSlots
+		}
+	}
+}
+
+func (s *syncScheduler) hostSlots(host string) chan struct{} {
+	if s.perHost <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slots, ok := s.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, s.perHost)
+		s.hosts[host] = slots
+	}
+	return slots
+}
+
+// retry runs fn until it succeeds or the retries are exhausted, waiting with an exponential backoff in between.
+// Every attempt acquires a slot with acquire, if set, and gets its own timeout once the slot is acquired.
+// onRetry is called before waiting for the next attempt.
+func (s SyncSchedule) retry(acquire func() func(), fn func(ctx context.Context) error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	delay := s.Backoff
+	for attempt := 0; ; attempt++ {
+		err := s.attempt(acquire, fn)
+		if err == nil || attempt >= s.Retries {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt+1, delay, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s SyncSchedule) attempt(acquire func() func(), fn func(ctx context.Context) error) error {
+	// Time spent waiting for a slot doesn't count against the timeout
+	if acquire != nil {
+		release := acquire()
+		defer release()
+	}
+	if s.Timeout <= 0 {
+		return fn(context.Background())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", s.Timeout, err)
+	}
+	return err
+}
+
+// sourceHost returns the host a vendir content is fetched from, or an empty string if it is not remote.
+func sourceHost(content vendirconf.DirectoryContents) string { //nolint:gocritic // external type
+	switch {
+	case content.HelmChart != nil && content.HelmChart.Repository != nil:
+		return urlHost(content.HelmChart.Repository.URL)
+	case content.Git != nil:
+		return urlHost(content.Git.URL)
+	case content.Image != nil:
+		return imageHost(content.Image.URL)
+	case content.ImgpkgBundle != nil:
+		return imageHost(content.ImgpkgBundle.Image)
+	case content.GithubRelease != nil:
+		return "github.com"
+	case content.HTTP != nil:
+		return urlHost(content.HTTP.URL)
+	}
+	return ""
+}
+
+// imageHost returns the registry of an OCI image reference, index.docker.io for Docker Hub references like `nginx:1.25`.
+func imageHost(ref string) string {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return urlHost(ref)
+	}
+	return parsed.Context().RegistryStr()
+}
+
+// urlHost extracts the host of a URL, an scp-like git remote, or an OCI image reference.
+func urlHost(location string) string {
+	if strings.Contains(location, "://") {
+		if u, err := url.Parse(location); err == nil {
+			return u.Hostname()
+		}
+		return ""
+	}
+	// git@github.com:org/repo or registry.example.com/image:tag
+	host, _, _ := strings.Cut(location, "/")
+	if _, after, ok := strings.Cut(host, "@"); ok {
+		host = after
+	}
+	host, _, _ = strings.Cut(host, ":")
+	return host
+}
@@ -0,0 +1,139 @@
+package myks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncSchedule_defaults(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	assert.Zero(t, g.SyncSchedule.Retries, "failed syncs are not retried by default")
+	assert.Equal(t, 2*time.Second, g.SyncSchedule.Backoff)
+	assert.Zero(t, g.SyncSchedule.Concurrency)
+}
+
+func TestSyncSchedule_retry(t *testing.T) {
+	t.Parallel()
+
+	s := SyncSchedule{Retries: 2, Backoff: time.Millisecond}
+	errFlaky := errors.New("flaky")
+
+	var calls int
+	var delays []time.Duration
+	err := s.retry(nil, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errFlaky
+		}
+		return nil
+	}, func(_ int, delay time.Duration, _ error) {
+		delays = append(delays, delay)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, delays, "backoff doubles")
+
+	calls = 0
+	err = s.retry(nil, func(context.Context) error {
+		calls++
+		return errFlaky
+	}, nil)
+	require.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 3, calls, "retries are exhausted")
+}
+
+func TestSyncSchedule_retryTimeout(t *testing.T) {
+	t.Parallel()
+
+	s := SyncSchedule{Timeout: 10 * time.Millisecond}
+	err := s.retry(nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "timed out after 10ms")
+
+	// Waiting for a slot doesn't count against the timeout
+	released := false
+	err = s.retry(func() func() {
+		time.Sleep(20 * time.Millisecond)
+		return func() { released = true }
+	}, func(ctx context.Context) error {
+		return ctx.Err()
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, released)
+}
+
+func TestSyncScheduler_acquire(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newSyncScheduler(SyncSchedule{Concurrency: 3, PerHostConcurrency: 1})
+	var running, maxRunning, maxPerHost atomic.Int64
+	perHost := map[string]*atomic.Int64{"a": {}, "b": {}}
+
+	var wg sync.WaitGroup
+	for i := range 12 {
+		host := []string{"a", "b"}[i%2]
+		wg.Go(func() {
+			release := scheduler.acquire(host)
+			defer release()
+			storeMax(&maxRunning, running.Add(1))
+			storeMax(&maxPerHost, perHost[host].Add(1))
+			time.Sleep(time.Millisecond)
+			perHost[host].Add(-1)
+			running.Add(-1)
+		})
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxRunning.Load(), int64(2), "one sync per host at most")
+	assert.Equal(t, int64(1), maxPerHost.Load())
+}
+
+func storeMax(target *atomic.Int64, value int64) {
+	for {
+		current := target.Load()
+		if value <= current || target.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+func TestSourceHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content vendirconf.DirectoryContents
+		want    string
+	}{
+		{"helm", vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{Repository: &vendirconf.DirectoryContentsHelmChartRepo{URL: "https://charts.example.com/stable"}}}, "charts.example.com"},
+		{"helm oci", vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{Repository: &vendirconf.DirectoryContentsHelmChartRepo{URL: "oci://ghcr.io/org/charts"}}}, "ghcr.io"},
+		{"git https", vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{URL: "https://github.com/org/repo"}}, "github.com"},
+		{"git scp", vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{URL: "git@gitlab.example.com:org/repo.git"}}, "gitlab.example.com"},
+		{"image", vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "registry.example.com:5000/org/image:v1"}}, "registry.example.com:5000"},
+		{"image docker hub", vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "nginx:1.25"}}, "index.docker.io"},
+		{"image docker hub org", vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "bitnami/redis"}}, "index.docker.io"},
+		{"image digest", vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{URL: "ghcr.io/org/image@sha256:" + strings.Repeat("a", 64)}}, "ghcr.io"},
+		{"bundle", vendirconf.DirectoryContents{ImgpkgBundle: &vendirconf.DirectoryContentsImgpkgBundle{Image: "index.docker.io/org/bundle"}}, "index.docker.io"},
+		{"bundle docker hub", vendirconf.DirectoryContents{ImgpkgBundle: &vendirconf.DirectoryContentsImgpkgBundle{Image: "docker.io/org/bundle:v1"}}, "index.docker.io"},
+		{"local", vendirconf.DirectoryContents{Directory: &vendirconf.DirectoryContentsDirectory{Path: "local"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, sourceHost(tt.content))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package myks
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	vendirconf "carvel.dev/vendir/pkg/vendir/config"
 	"github.com/rs/zerolog/log"
@@ -57,6 +59,36 @@ type VendirSyncer struct {
 	// Used to ensure each cache's vendir.yaml is written exactly once across all concurrent apps.
 	// When two goroutines produce different configs for the same cache name, the mismatch is detected and returned as an error.
 	cacheConfigResults sync.Map
+	// lockedDigests holds the locked content digests of pinned cache entries.
+	// Key: cache name, Value: string
+	lockedDigests sync.Map
+	// cacheLocks holds the resolved versions of cache entries synced in lock mode.
+	// Key: cache name, Value: cacheLock
+	cacheLocks sync.Map
+	// cacheHosts holds the hosts the cache entries are fetched from, mirrors applied.
+	// Key: cache name, Value: string
+	cacheHosts sync.Map
+	// helmCaches tracks whether each cache entry is a helm chart, synced with helmSecrets.
+	// Key: cache name, Value: bool
+	helmCaches sync.Map
+	// helmSecrets are the vendir secrets in the form supported by helm chart sources, set by GenerateSecrets
+	helmSecrets string
+	// cacheRefsMu serializes updates of the shared cache references of the project
+	cacheRefsMu sync.Mutex
+	// scheduler limits concurrent vendir runs according to Schedule
+	scheduler     *syncScheduler
+	schedulerOnce sync.Once
+
+	// UpdateLock resolves all sources again and rewrites the application lock files
+	UpdateLock bool
+	// Frozen fails the sync if an application lock file is missing or outdated
+	Frozen bool
+	// Offline never runs vendir, sources are linked from the cache and missing ones fail the sync
+	Offline bool
+	// Mirrors rewrite source URLs in the cache vendir configs, cache names keep the original URLs
+	Mirrors SourceMirrors
+	// Schedule sets retries, timeouts, and concurrency limits of vendir runs
+	Schedule SyncSchedule
 
 	// Dedup counters for observability
 	SyncExecuted        atomic.Int64
@@ -97,13 +129,30 @@ func (v *VendirSyncer) Sync(a *Application, vendirSecrets string) error {
 		return fmt.Errorf("rendering vendir config for %s: %w", a.Name, err)
 	}
 
-	if err := v.extractCacheItems(a); err != nil {
+	configHash, err := v.vendirConfigHash(a)
+	if err != nil {
+		return fmt.Errorf("hashing vendir config for %s: %w", a.Name, err)
+	}
+
+	appLock, err := v.loadAppLock(a, configHash)
+	if err != nil {
+		return fmt.Errorf("loading lock file for %s: %w", a.Name, err)
+	}
+
+	sources, err := v.extractCacheItems(a, appLock)
+	if err != nil {
 		return fmt.Errorf("extracting cache items for %s: %w", a.Name, err)
 	}
 
 	if err := v.doSync(a, vendirSecrets); err != nil {
 		return fmt.Errorf("syncing %s: %w", a.Name, err)
 	}
+
+	if v.UpdateLock {
+		if err := v.saveAppLock(a, configHash, sources); err != nil {
+			return fmt.Errorf("locking %s: %w", a.Name, err)
+		}
+	}
 	log.Info().Msg(a.Msg(v.getStepName(), "Synced"))
 	return nil
 }
@@ -149,6 +198,11 @@ func (v *VendirSyncer) renderVendirConfig(a *Application) error {
 		return err
 	}
 	if len(vendirFiles) == 0 {
+		if v.UpdateLock {
+			if err := a.removeAppLock(); err != nil {
+				return err
+			}
+		}
 		return ErrNoVendirConfig
 	}
 
@@ -190,12 +244,26 @@ func (v *VendirSyncer) doSync(a *Application, vendirSecrets string) error {
 		return fmt.Errorf("reading vendir links map: %w", err)
 	}
 
+	if err := v.registerCacheRefs(a, linksMap); err != nil {
+		return fmt.Errorf("registering shared cache references: %w", err)
+	}
+
+	// In offline mode, sources missing from the cache are left unlinked and reported after linking the others
+	var missing map[string]bool
+	var offlineErr error
+	if v.Offline {
+		missing, offlineErr = v.checkOfflineCache(a, linksMap)
+	}
+
 	if err := os.RemoveAll(a.expandVendorPath("")); err != nil {
 		log.Warn().Err(err).Msg(a.Msg(v.getStepName(), "Unable to remove vendor directory"))
 		return err
 	}
 
 	for contentPath, cacheName := range linksMap {
+		if missing[contentPath] {
+			continue
+		}
 		if err := v.syncCacheEntry(a, cacheName, vendirSecrets); err != nil {
 			return err
 		}
@@ -205,7 +273,7 @@ func (v *VendirSyncer) doSync(a *Application, vendirSecrets string) error {
 		}
 	}
 
-	return nil
+	return offlineErr
 }
 
 // isCachePopulated checks if a cache entry already has synced data from a previous run.
@@ -228,6 +296,7 @@ func (v *VendirSyncer) isCachePopulated(a *Application, cacheName string) bool {
 // syncCacheEntry ensures a cache entry is synced, using two levels of deduplication:
 // 1. Within-run: only one goroutine syncs each cache entry; others wait and reuse the result.
 // 2. Cross-run: if the cache is already populated on disk and the content is lazy, skip vendir entirely.
+// In offline mode, vendir is never run.
 func (v *VendirSyncer) syncCacheEntry(a *Application, cacheName, vendirSecrets string) error {
 	// Within-run dedup: try to claim ownership of this cache entry
 	result := &syncResult{done: make(chan struct{})}
@@ -246,25 +315,53 @@ func (v *VendirSyncer) syncCacheEntry(a *Application, cacheName, vendirSecrets s
 	// We own this cache entry — perform the sync
 	defer close(result.done)
 
+	// In offline mode, the cache entry has been checked before linking; lazy settings don't matter
+	if v.Offline {
+		v.SyncSkippedCached.Add(1)
+		touchCacheEntry(a, cacheName)
+		log.Debug().Str("cache", cacheName).Msg(a.Msg(v.getStepName(), "Skipped vendir sync (offline)"))
+		return nil
+	}
+
+	// Acquire lock, so the entry is not checked while another process syncs or removes it
+	unlock := v.locker.LockNames(slices.Values([]string{cacheName}), true)
+	defer unlock()
+
 	// Cross-run dedup: check if cache is already populated on disk
 	lazyVal, _ := v.lazyCaches.Load(cacheName)
 	isLazy, _ := lazyVal.(bool)
-	if isLazy && v.isCachePopulated(a, cacheName) {
+	if isLazy && !v.UpdateLock && v.isCachePopulated(a, cacheName) && isCacheDigestValid(a, cacheName) {
 		v.SyncSkippedCached.Add(1)
+		touchCacheEntry(a, cacheName)
 		log.Debug().Str("cache", cacheName).Msg(a.Msg(v.getStepName(), "Skipped vendir sync (cache already populated)"))
 		return nil
 	}
 
-	// Acquire lock and run vendir
-	unlock := v.locker.LockNames(slices.Values([]string{cacheName}), true)
-	defer unlock()
-
 	cacheDir := a.expandVendirCache(cacheName)
 	vendirConfigPath := filepath.Join(cacheDir, a.cfg.VendirConfigFileName)
 	vendirLockPath := filepath.Join(cacheDir, a.cfg.VendirLockFileName)
 
+	// Helm chart sources of vendir fail on secret fields other than a username and a password
+	helmVal, _ := v.helmCaches.Load(cacheName)
+	if isHelm, _ := helmVal.(bool); isHelm {
+		vendirSecrets = v.helmSecrets
+	}
+
 	v.SyncExecuted.Add(1)
-	syncErr := v.runVendirSync(a, vendirConfigPath, vendirLockPath, vendirSecrets)
+	syncErr := v.runScheduledVendirSync(a, cacheName, vendirConfigPath, vendirLockPath, vendirSecrets)
+	if syncErr == nil {
+		syncErr = v.verifyCacheDigest(a, cacheName)
+	}
+	if syncErr == nil {
+		syncErr = writeCacheDigest(a, cacheName)
+	}
+	if syncErr == nil && v.UpdateLock {
+		var resolved cacheLock
+		if resolved, syncErr = readCacheLock(a, cacheName); syncErr == nil {
+			resolved.content = v.Mirrors.restoreLock(resolved.content)
+			v.cacheLocks.Store(cacheName, resolved)
+		}
+	}
 
 	if syncErr != nil {
 		log.Error().Err(syncErr).Msg(a.Msg(v.getStepName(), "Vendir sync failed, cleaning up the cache entry"))
@@ -275,6 +372,7 @@ func (v *VendirSyncer) syncCacheEntry(a *Application, cacheName, vendirSecrets s
 		return syncErr
 	}
 
+	touchCacheEntry(a, cacheName)
 	return nil
 }
 
@@ -283,9 +381,13 @@ func (v *VendirSyncer) linkVendorToCache(a *Application, vendorPath, cacheName s
 	linkDir := filepath.Dir(linkFullPath)
 	cacheDataPath := path.Join(a.expandVendirCache(cacheName), VendirCacheDataDirName)
 
-	relCacheDataPath, err := filepath.Rel(linkDir, cacheDataPath)
-	if err != nil {
-		return fmt.Errorf("computing relative cache path from %s to %s: %w", linkDir, cacheDataPath, err)
+	// Links to the shared cache are absolute, as the cache is outside the project
+	relCacheDataPath := cacheDataPath
+	if !filepath.IsAbs(cacheDataPath) {
+		var err error
+		if relCacheDataPath, err = filepath.Rel(linkDir, cacheDataPath); err != nil {
+			return fmt.Errorf("computing relative cache path from %s to %s: %w", linkDir, cacheDataPath, err)
+		}
 	}
 
 	if err := createDirectory(linkDir); err != nil {
@@ -298,8 +400,22 @@ func (v *VendirSyncer) linkVendorToCache(a *Application, vendorPath, cacheName s
 	return nil
 }
 
-func (v *VendirSyncer) runVendirSync(a *Application, vendirConfig, vendirLock, vendirSecrets string) error {
-	// TODO sync retry - maybe as vendir MR
+// runScheduledVendirSync runs vendir for a cache entry within the concurrency limits of its host, retrying on failure.
+func (v *VendirSyncer) runScheduledVendirSync(a *Application, cacheName, vendirConfig, vendirLock, vendirSecrets string) error {
+	v.schedulerOnce.Do(func() { v.scheduler = newSyncScheduler(v.Schedule) })
+	hostVal, _ := v.cacheHosts.Load(cacheName)
+	host, _ := hostVal.(string)
+	return v.Schedule.retry(func() func() {
+		return v.scheduler.acquire(host)
+	}, func(ctx context.Context) error {
+		return v.runVendirSync(ctx, a, vendirConfig, vendirLock, vendirSecrets)
+	}, func(attempt int, delay time.Duration, err error) {
+		log.Warn().Err(err).Str("cache", cacheName).Int("attempt", attempt).Dur("backoff", delay).
+			Msg(a.Msg(v.getStepName(), "Vendir sync failed, retrying"))
+	})
+}
+
+func (v *VendirSyncer) runVendirSync(ctx context.Context, a *Application, vendirConfig, vendirLock, vendirSecrets string) error {
 	args := []string{
 		"vendir",
 		"sync",
@@ -307,7 +423,7 @@ func (v *VendirSyncer) runVendirSync(a *Application, vendirConfig, vendirLock, v
 		"--lock-file=" + vendirLock,
 		"--file=-",
 	}
-	_, err := a.runCmd(v.getStepName(), "vendir sync", myksFullPath(), strings.NewReader(vendirSecrets), args)
+	_, err := a.runCmdContext(ctx, v.getStepName(), "vendir sync", myksFullPath(), strings.NewReader(vendirSecrets), args)
 	return err
 }
 
@@ -315,35 +431,63 @@ func (v *VendirSyncer) getStepName() string {
 	return fmt.Sprintf("%s-%s", syncStepName, v.Ident())
 }
 
-func (v *VendirSyncer) extractCacheItems(a *Application) error {
+// vendirConfigHash returns the hash of the rendered vendir config of the application.
+func (v *VendirSyncer) vendirConfigHash(a *Application) (string, error) {
+	configBytes, err := os.ReadFile(a.expandServicePath(a.cfg.VendirConfigFileName))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vendir config: %w", err)
+	}
+	return hashString(string(configBytes))
+}
+
+// extractCacheItems splits the rendered vendir config of the application into per-cache configs.
+// Sources locked in appLock are pinned to their resolved versions.
+// Returns the cache names of the sources as defined in the vendir config, keyed by vendor path.
+func (v *VendirSyncer) extractCacheItems(a *Application, appLock *AppLock) (map[string]string, error) {
 	configPath := a.expandServicePath(a.cfg.VendirConfigFileName)
 	configBytes, err := os.ReadFile(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read vendir config: %w", err)
+		return nil, fmt.Errorf("failed to read vendir config: %w", err)
 	}
 
 	// Unmarshal directly without validation (NewConfigFromBytes validates which
 	// may reject certain valid-for-our-use configs like those with "." paths)
 	var vendirConfig vendirconf.Config
 	if err := yaml.Unmarshal(configBytes, &vendirConfig); err != nil {
-		return fmt.Errorf("failed to parse vendir config: %w", err)
+		return nil, fmt.Errorf("failed to parse vendir config: %w", err)
 	}
 
 	vendorDirToCacheMap := map[string]string{}
+	sources := map[string]string{}
 	cacheVendirConfigs := map[string]vendirconf.Config{}
 
 	for _, dir := range vendirConfig.Directories {
 		for i := range dir.Contents {
 			content := &dir.Contents[i]
 			vendorDirPath := filepath.Join(dir.Path, content.Path)
-			cacheName, err := genCacheName(*content)
+			source, err := genCacheName(*content)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			sources[vendorDirPath] = source
+			cacheName := source
+			if locked, ok := appLock.find(filepath.ToSlash(vendorDirPath), source); ok {
+				pinned := pinContent(*content, locked)
+				content = &pinned
+				if cacheName, err = genCacheName(pinned); err != nil {
+					return nil, err
+				}
+				v.lockedDigests.Store(cacheName, locked.Digest)
+			} else if appLock != nil {
+				log.Warn().Str("path", vendorDirPath).Msg(a.Msg(v.getStepName(), "Source is not locked, run `myks lock` to pin it"))
 			}
 			vendorDirToCacheMap[vendorDirPath] = cacheName
 			cacheDir := a.expandVendirCache(cacheName)
-			cacheVendirConfigs[cacheName] = buildCacheVendirConfig(cacheDir, vendirConfig, dir, content)
+			mirrored := v.Mirrors.apply(*content)
+			cacheVendirConfigs[cacheName] = buildCacheVendirConfig(cacheDir, vendirConfig, dir, &mirrored)
 			v.lazyCaches.Store(cacheName, content.Lazy)
+			v.cacheHosts.Store(cacheName, sourceHost(mirrored))
+			v.helmCaches.Store(cacheName, content.HelmChart != nil)
 		}
 	}
 
@@ -353,14 +497,14 @@ func (v *VendirSyncer) extractCacheItems(a *Application) error {
 	// any common cache entry even though they produce identical config content.
 	for cacheName, cacheVendirConfig := range cacheVendirConfigs {
 		if err := v.ensureCacheConfig(a, cacheName, cacheVendirConfig); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Save the per-app links map after the cache configs are successfully ensured,
 	// so the on-disk links map only points at cache entries whose vendir.yaml was
 	// successfully written.
-	return v.saveLinksMap(a, vendorDirToCacheMap)
+	return sources, v.saveLinksMap(a, vendorDirToCacheMap)
 }
 
 // ensureCacheConfig writes the per-cache vendir config file exactly once per run using a
@@ -398,7 +542,7 @@ func (v *VendirSyncer) ensureCacheConfig(a *Application, cacheName string, confi
 
 func (v *VendirSyncer) writeCacheVendirConfig(a *Application, cacheName string, data []byte) error {
 	vendirConfigPath := filepath.Join(a.expandVendirCache(cacheName), a.cfg.VendirConfigFileName)
-	if err := writeFile(vendirConfigPath, data); err != nil {
+	if err := writeFileAtomic(vendirConfigPath, data); err != nil {
 		log.Warn().Err(err).Msg(a.Msg(v.getStepName(), "Unable to write vendir config"))
 		return err
 	}
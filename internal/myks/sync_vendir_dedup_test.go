@@ -3,9 +3,11 @@ package myks
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	vendirconf "carvel.dev/vendir/pkg/vendir/config"
 	"github.com/stretchr/testify/assert"
@@ -186,6 +188,33 @@ func TestSyncCacheEntryCrossRunDedup(t *testing.T) {
 	assert.Equal(t, int64(0), v.SyncExecuted.Load())
 }
 
+func TestSyncCacheEntryCrossRunDedupWaitsForLock(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, t.TempDir(), "locked-cache")
+	app := g.collectAllApplications()[0]
+	require.NoError(t, os.WriteFile(filepath.Join(app.expandVendirCache("locked-cache"), g.VendirLockFileName), []byte("lock"), 0o600))
+	require.NoError(t, writeCacheDigest(app, "locked-cache"))
+
+	v := NewVendirSyncer(locker.NewLocker())
+	v.lazyCaches.Store("locked-cache", true)
+
+	// Another sync of the entry is in progress
+	unlock := v.locker.LockNames(slices.Values([]string{"locked-cache"}), true)
+	done := make(chan error)
+	go func() { done <- v.syncCacheEntry(app, "locked-cache", "") }()
+	select {
+	case <-done:
+		t.Fatal("cache entry was checked without the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(1), v.SyncSkippedCached.Load())
+	assert.Equal(t, int64(0), v.SyncExecuted.Load())
+}
+
 func TestSyncCacheEntryNoSkipWhenNotLazy(t *testing.T) {
 	// Not parallel: uses t.Setenv which requires sequential execution.
 	tmpDir := t.TempDir()
@@ -425,18 +454,28 @@ func TestFindCacheNameForChart(t *testing.T) {
 	linksMap := map[string]string{
 		jn("charts", "nginx"):      "helm-nginx-1.0.0-abc123",
 		jn("charts", "prometheus"): "helm-prometheus-2.0.0-def456",
+		"repo":                     "git-repo-abc123",
+		jn("repo", "nested"):       "git-nested-def456",
 	}
 
 	t.Run("finds cache name for known chart", func(t *testing.T) {
 		t.Parallel()
-		result := findCacheNameForChart(linksMap, jn("some", "vendor", "charts", "nginx"), "charts")
-		assert.Equal(t, "helm-nginx-1.0.0-abc123", result)
+		cacheName, subPath := findCacheNameForChart(linksMap, jn("charts", "nginx"))
+		assert.Equal(t, "helm-nginx-1.0.0-abc123", cacheName)
+		assert.Empty(t, subPath)
 	})
 
 	t.Run("returns empty string for unknown chart", func(t *testing.T) {
 		t.Parallel()
-		result := findCacheNameForChart(linksMap, jn("some", "vendor", "charts", "unknown"), "charts")
-		assert.Empty(t, result)
+		cacheName, subPath := findCacheNameForChart(linksMap, jn("charts", "unknown"))
+		assert.Empty(t, cacheName)
+		assert.Empty(t, subPath)
+	})
+
+	t.Run("returns empty string for charts outside of vendor directory", func(t *testing.T) {
+		t.Parallel()
+		cacheName, _ := findCacheNameForChart(linksMap, "")
+		assert.Empty(t, cacheName)
 	})
 
 	t.Run("handles custom helmChartsDirName", func(t *testing.T) {
@@ -444,8 +483,28 @@ func TestFindCacheNameForChart(t *testing.T) {
 		customLinksMap := map[string]string{
 			jn("helm-charts", "nginx"): "helm-nginx-1.0.0-abc123",
 		}
-		result := findCacheNameForChart(customLinksMap, jn("vendor", "helm-charts", "nginx"), "helm-charts")
-		assert.Equal(t, "helm-nginx-1.0.0-abc123", result)
+		cacheName, _ := findCacheNameForChart(customLinksMap, jn("helm-charts", "nginx"))
+		assert.Equal(t, "helm-nginx-1.0.0-abc123", cacheName)
+	})
+
+	t.Run("finds chart in a sub-path of a cached directory", func(t *testing.T) {
+		t.Parallel()
+		cacheName, subPath := findCacheNameForChart(linksMap, jn("repo", "deploy", "chart"))
+		assert.Equal(t, "git-repo-abc123", cacheName)
+		assert.Equal(t, jn("deploy", "chart"), subPath)
+	})
+
+	t.Run("prefers the longest matching vendor path", func(t *testing.T) {
+		t.Parallel()
+		cacheName, subPath := findCacheNameForChart(linksMap, jn("repo", "nested", "chart"))
+		assert.Equal(t, "git-nested-def456", cacheName)
+		assert.Equal(t, "chart", subPath)
+	})
+
+	t.Run("does not match vendor path prefixes that are not directories", func(t *testing.T) {
+		t.Parallel()
+		cacheName, _ := findCacheNameForChart(linksMap, jn("repository", "chart"))
+		assert.Empty(t, cacheName)
 	})
 }
 
@@ -0,0 +1,222 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+const appLockFileHeader = "# Generated by `myks lock`. DO NOT EDIT.\n"
+
+// ErrAppLockOutdated is returned in frozen mode when an application lock file is missing or does not match the vendir config.
+var ErrAppLockOutdated = errors.New("lock file is missing or outdated, run `myks lock`")
+
+// AppLock holds the resolved versions of the vendir sources of an application.
+// It is stored next to the application configuration and is meant to be committed,
+// so that every sync of the same commit fetches exactly the same content.
+type AppLock struct {
+	// Hash of the rendered vendir config the lock was created from
+	VendirConfigHash string `json:"vendirConfigHash"`
+	// Resolved sources, sorted by path
+	Contents []AppLockContent `json:"contents"`
+}
+
+// AppLockContent holds the resolved version of a single vendir source.
+type AppLockContent struct {
+	// Path of the content relative to the vendor directory, e.g. charts/nginx
+	Path string `json:"path"`
+	// Cache name of the source as defined in the vendir config, used to detect changes
+	Source string `json:"source"`
+	// Hash of the synced content
+	Digest string `json:"digest"`
+
+	Git           *vendirconf.LockDirectoryContentsGit           `json:"git,omitempty"`
+	HelmChart     *vendirconf.LockDirectoryContentsHelmChart     `json:"helmChart,omitempty"`
+	Image         *vendirconf.LockDirectoryContentsImage         `json:"image,omitempty"`
+	ImgpkgBundle  *vendirconf.LockDirectoryContentsImgpkgBundle  `json:"imgpkgBundle,omitempty"`
+	GithubRelease *vendirconf.LockDirectoryContentsGithubRelease `json:"githubRelease,omitempty"`
+}
+
+// cacheLock holds the resolved version of a synced cache entry.
+type cacheLock struct {
+	digest  string
+	content vendirconf.LockDirectoryContents
+}
+
+func (a *Application) getAppLockPath() string {
+	return filepath.Join(a.e.Dir, a.cfg.AppsDir, a.Name, a.cfg.AppLockFileName)
+}
+
+// readAppLock reads the lock file of the application. Returns nil if the lock file does not exist.
+func (a *Application) readAppLock() (*AppLock, error) {
+	data, err := os.ReadFile(a.getAppLockPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var lock AppLock
+	if err = yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parsing lock file %s: %w", a.getAppLockPath(), err)
+	}
+	return &lock, nil
+}
+
+func (a *Application) writeAppLock(lock *AppLock) error {
+	slices.SortFunc(lock.Contents, func(x, y AppLockContent) int {
+		return strings.Compare(x.Path, y.Path)
+	})
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return writeFile(a.getAppLockPath(), append([]byte(appLockFileHeader), data...))
+}
+
+func (a *Application) removeAppLock() error {
+	if err := os.Remove(a.getAppLockPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// find returns the lock entry of the given vendor path, if it was locked from the same source.
+func (l *AppLock) find(vendorPath, source string) (AppLockContent, bool) {
+	if l == nil {
+		return AppLockContent{}, false
+	}
+	for _, c := range l.Contents {
+		if c.Path == vendorPath && c.Source == source {
+			return c, true
+		}
+	}
+	return AppLockContent{}, false
+}
+
+// loadAppLock loads the lock of the application for the current sync.
+// In lock mode, the existing lock is ignored as sources are resolved again.
+// In frozen mode, the lock must exist and match the rendered vendir config.
+func (v *VendirSyncer) loadAppLock(a *Application, vendirConfigHash string) (*AppLock, error) {
+	if v.UpdateLock {
+		return nil, nil
+	}
+	lock, err := a.readAppLock()
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil && lock.VendirConfigHash == vendirConfigHash {
+		return lock, nil
+	}
+	if v.Frozen {
+		return nil, fmt.Errorf("%s: %w", a.getAppLockPath(), ErrAppLockOutdated)
+	}
+	if lock != nil {
+		log.Warn().Str("lock", a.getAppLockPath()).Msg(a.Msg(v.getStepName(), "Vendir config changed since the last `myks lock`, unchanged sources stay pinned"))
+	}
+	return lock, nil
+}
+
+// pinContent returns a copy of the content that fetches exactly the locked version.
+// Sources without a resolvable version (e.g. directory, inline, http) are returned unchanged;
+// their content is still verified against the locked digest.
+func pinContent(content vendirconf.DirectoryContents, locked AppLockContent) vendirconf.DirectoryContents { //nolint:gocritic // external type
+	switch {
+	case content.Git != nil && locked.Git != nil && locked.Git.SHA != "":
+		git := *content.Git
+		git.Ref = locked.Git.SHA
+		git.RefSelection = nil
+		content.Git = &git
+	case content.HelmChart != nil && locked.HelmChart != nil && locked.HelmChart.Version != "":
+		chart := *content.HelmChart
+		chart.Version = locked.HelmChart.Version
+		content.HelmChart = &chart
+	case content.Image != nil && locked.Image != nil && locked.Image.URL != "":
+		image := *content.Image
+		image.URL = locked.Image.URL
+		image.TagSelection = nil
+		content.Image = &image
+	case content.ImgpkgBundle != nil && locked.ImgpkgBundle != nil && locked.ImgpkgBundle.Image != "":
+		bundle := *content.ImgpkgBundle
+		bundle.Image = locked.ImgpkgBundle.Image
+		bundle.TagSelection = nil
+		content.ImgpkgBundle = &bundle
+	case content.GithubRelease != nil && locked.GithubRelease != nil && locked.GithubRelease.Tag != "":
+		release := *content.GithubRelease
+		release.Tag = locked.GithubRelease.Tag
+		release.Latest = false
+		release.TagSelection = nil
+		content.GithubRelease = &release
+	}
+	return content
+}
+
+// readCacheLock reads the vendir lock file of a synced cache entry and computes the digest of its content.
+func readCacheLock(a *Application, cacheName string) (cacheLock, error) {
+	cacheDir := a.expandVendirCache(cacheName)
+	vendirLock, err := vendirconf.NewLockConfigFromFile(filepath.Join(cacheDir, a.cfg.VendirLockFileName))
+	if err != nil {
+		return cacheLock{}, err
+	}
+	if len(vendirLock.Directories) != 1 || len(vendirLock.Directories[0].Contents) != 1 {
+		return cacheLock{}, fmt.Errorf("unexpected vendir lock file of cache %s", cacheName)
+	}
+	digest, err := hashDirectory(filepath.Join(cacheDir, VendirCacheDataDirName))
+	if err != nil {
+		return cacheLock{}, err
+	}
+	return cacheLock{digest: digest, content: vendirLock.Directories[0].Contents[0]}, nil
+}
+
+// verifyCacheDigest checks the freshly synced content of a cache entry against the locked digest.
+func (v *VendirSyncer) verifyCacheDigest(a *Application, cacheName string) error {
+	expected, ok := v.lockedDigests.Load(cacheName)
+	if !ok || expected.(string) == "" {
+		return nil
+	}
+	digest, err := hashDirectory(filepath.Join(a.expandVendirCache(cacheName), VendirCacheDataDirName))
+	if err != nil {
+		return err
+	}
+	if digest != expected.(string) {
+		return fmt.Errorf("content of cache %s does not match the locked digest (got %s, want %s)", cacheName, digest, expected)
+	}
+	return nil
+}
+
+// saveAppLock writes the lock file of the application from the resolved cache entries.
+func (v *VendirSyncer) saveAppLock(a *Application, vendirConfigHash string, sources map[string]string) error {
+	linksMap, err := a.getLinksMap()
+	if err != nil {
+		return err
+	}
+	lock := &AppLock{VendirConfigHash: vendirConfigHash}
+	for vendorPath, cacheName := range linksMap {
+		value, ok := v.cacheLocks.Load(cacheName)
+		if !ok {
+			return fmt.Errorf("cache %s was not resolved", cacheName)
+		}
+		resolved := value.(cacheLock)
+		lock.Contents = append(lock.Contents, AppLockContent{
+			Path:          filepath.ToSlash(vendorPath),
+			Source:        sources[vendorPath],
+			Digest:        resolved.digest,
+			Git:           resolved.content.Git,
+			HelmChart:     resolved.content.HelmChart,
+			Image:         resolved.content.Image,
+			ImgpkgBundle:  resolved.content.ImgpkgBundle,
+			GithubRelease: resolved.content.GithubRelease,
+		})
+	}
+	if err = a.writeAppLock(lock); err != nil {
+		return fmt.Errorf("writing lock file: %w", err)
+	}
+	log.Info().Str("lock", a.getAppLockPath()).Msg(a.Msg(v.getStepName(), "Lock file updated"))
+	return nil
+}
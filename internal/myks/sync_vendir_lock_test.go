@@ -0,0 +1,255 @@
+package myks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vendirconf "carvel.dev/vendir/pkg/vendir/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+func newLockTestApp(t *testing.T) *Application {
+	t.Helper()
+	rootDir := t.TempDir()
+	cfg := &Config{
+		RootDir:                rootDir,
+		ServiceDirName:         ".myks",
+		VendirCache:            "vendir-cache",
+		AppsDir:                "_apps",
+		AppLockFileName:        "myks.lock.yaml",
+		VendirConfigFileName:   "vendir.yaml",
+		VendirLockFileName:     "vendir.lock.yaml",
+		VendirLinksMapFileName: "vendir-links.yaml",
+	}
+	return &Application{
+		Name: "app",
+		cfg:  cfg,
+		e:    &Environment{ID: "env", Dir: filepath.Join(rootDir, "envs", "env")},
+	}
+}
+
+func TestPinContent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content vendirconf.DirectoryContents
+		locked  AppLockContent
+		want    vendirconf.DirectoryContents
+	}{
+		{
+			name: "git ref is pinned to sha",
+			content: vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{
+				URL: "https://github.com/org/repo", Ref: "main",
+			}},
+			locked: AppLockContent{Git: &vendirconf.LockDirectoryContentsGit{SHA: "0123abcd"}},
+			want: vendirconf.DirectoryContents{Git: &vendirconf.DirectoryContentsGit{
+				URL: "https://github.com/org/repo", Ref: "0123abcd",
+			}},
+		},
+		{
+			name: "helm chart version is pinned",
+			content: vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{
+				Name: "nginx", Version: "1.x",
+			}},
+			locked: AppLockContent{HelmChart: &vendirconf.LockDirectoryContentsHelmChart{Version: "1.2.3"}},
+			want: vendirconf.DirectoryContents{HelmChart: &vendirconf.DirectoryContentsHelmChart{
+				Name: "nginx", Version: "1.2.3",
+			}},
+		},
+		{
+			name: "image tag is pinned to digest",
+			content: vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{
+				URL: "ghcr.io/org/config:latest",
+			}},
+			locked: AppLockContent{Image: &vendirconf.LockDirectoryContentsImage{URL: "ghcr.io/org/config@sha256:abc"}},
+			want: vendirconf.DirectoryContents{Image: &vendirconf.DirectoryContentsImage{
+				URL: "ghcr.io/org/config@sha256:abc",
+			}},
+		},
+		{
+			name: "latest github release is pinned to tag",
+			content: vendirconf.DirectoryContents{GithubRelease: &vendirconf.DirectoryContentsGithubRelease{
+				Slug: "org/repo", Latest: true,
+			}},
+			locked: AppLockContent{GithubRelease: &vendirconf.LockDirectoryContentsGithubRelease{Tag: "v1.0.0"}},
+			want: vendirconf.DirectoryContents{GithubRelease: &vendirconf.DirectoryContentsGithubRelease{
+				Slug: "org/repo", Tag: "v1.0.0",
+			}},
+		},
+		{
+			name: "directory is not changed",
+			content: vendirconf.DirectoryContents{Directory: &vendirconf.DirectoryContentsDirectory{
+				Path: "local",
+			}},
+			locked: AppLockContent{Digest: "abc"},
+			want: vendirconf.DirectoryContents{Directory: &vendirconf.DirectoryContentsDirectory{
+				Path: "local",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			original, err := contentToStableYAML(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pinContent(tt.content, tt.locked))
+			// The original content must not be modified
+			after, err := contentToStableYAML(tt.content)
+			require.NoError(t, err)
+			assert.Equal(t, original, after)
+		})
+	}
+}
+
+func TestAppLock_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	app := newLockTestApp(t)
+	lock, err := app.readAppLock()
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+
+	written := &AppLock{
+		VendirConfigHash: "cfg",
+		Contents: []AppLockContent{
+			{Path: "ytt/repo", Source: "git-repo-main-1", Digest: "d2", Git: &vendirconf.LockDirectoryContentsGit{SHA: "abc", CommitTitle: "fix"}},
+			{Path: "charts/nginx", Source: "helm-nginx-1.0.0-2", Digest: "d1", HelmChart: &vendirconf.LockDirectoryContentsHelmChart{Version: "1.0.0", AppVersion: "1.25"}},
+		},
+	}
+	require.NoError(t, app.writeAppLock(written))
+
+	data, err := os.ReadFile(app.getAppLockPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), appLockFileHeader)
+
+	lock, err = app.readAppLock()
+	require.NoError(t, err)
+	assert.Equal(t, written, lock)
+	assert.Equal(t, "charts/nginx", lock.Contents[0].Path, "contents are sorted by path")
+
+	locked, ok := lock.find("ytt/repo", "git-repo-main-1")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", locked.Git.SHA)
+	_, ok = lock.find("ytt/repo", "git-repo-develop-1")
+	assert.False(t, ok, "changed source must not be pinned")
+
+	require.NoError(t, app.removeAppLock())
+	require.NoError(t, app.removeAppLock())
+	assert.NoFileExists(t, app.getAppLockPath())
+}
+
+func TestVendirSyncer_loadAppLock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		lock       *AppLock
+		frozen     bool
+		updateLock bool
+		wantLock   bool
+		wantErr    error
+	}{
+		{name: "no lock", wantLock: false},
+		{name: "no lock in frozen mode", frozen: true, wantErr: ErrAppLockOutdated},
+		{name: "matching lock", lock: &AppLock{VendirConfigHash: "hash"}, wantLock: true},
+		{name: "matching lock in frozen mode", lock: &AppLock{VendirConfigHash: "hash"}, frozen: true, wantLock: true},
+		{name: "outdated lock", lock: &AppLock{VendirConfigHash: "old"}, wantLock: true},
+		{name: "outdated lock in frozen mode", lock: &AppLock{VendirConfigHash: "old"}, frozen: true, wantErr: ErrAppLockOutdated},
+		{name: "lock is ignored when updating", lock: &AppLock{VendirConfigHash: "old"}, frozen: true, updateLock: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app := newLockTestApp(t)
+			if tt.lock != nil {
+				require.NoError(t, app.writeAppLock(tt.lock))
+			}
+			v := NewVendirSyncer(locker.NewLocker())
+			v.Frozen = tt.frozen
+			v.UpdateLock = tt.updateLock
+
+			lock, err := v.loadAppLock(app, "hash")
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLock, lock != nil)
+		})
+	}
+}
+
+func TestVendirSyncer_extractCacheItemsPinsLockedSources(t *testing.T) {
+	t.Parallel()
+
+	app := newLockTestApp(t)
+	vendirConfig := `apiVersion: vendir.k14s.io/v1alpha1
+kind: Config
+directories:
+  - path: ytt
+    contents:
+      - path: repo
+        git:
+          url: https://github.com/org/repo
+          ref: main
+      - path: other
+        git:
+          url: https://github.com/org/other
+          ref: main
+`
+	require.NoError(t, writeFile(app.expandServicePath(app.cfg.VendirConfigFileName), []byte(vendirConfig)))
+
+	v := NewVendirSyncer(locker.NewLocker())
+	unpinned, err := v.extractCacheItems(app, nil)
+	require.NoError(t, err)
+	repoSource := unpinned[filepath.Join("ytt", "repo")]
+	otherSource := unpinned[filepath.Join("ytt", "other")]
+
+	appLock := &AppLock{Contents: []AppLockContent{
+		{Path: "ytt/repo", Source: repoSource, Digest: "digest", Git: &vendirconf.LockDirectoryContentsGit{SHA: "0123abcd"}},
+		{Path: "ytt/other", Source: "outdated-source", Git: &vendirconf.LockDirectoryContentsGit{SHA: "fedc4321"}},
+	}}
+	v = NewVendirSyncer(locker.NewLocker())
+	sources, err := v.extractCacheItems(app, appLock)
+	require.NoError(t, err)
+	assert.Equal(t, unpinned, sources, "sources always refer to the unpinned config")
+
+	linksMap, err := app.getLinksMap()
+	require.NoError(t, err)
+	pinnedCache := linksMap[filepath.Join("ytt", "repo")]
+	assert.Contains(t, pinnedCache, "0123abcd")
+	assert.Equal(t, otherSource, linksMap[filepath.Join("ytt", "other")], "outdated entries are not pinned")
+
+	digest, ok := v.lockedDigests.Load(pinnedCache)
+	assert.True(t, ok)
+	assert.Equal(t, "digest", digest)
+
+	cacheConfig, err := os.ReadFile(filepath.Join(app.expandVendirCache(pinnedCache), app.cfg.VendirConfigFileName))
+	require.NoError(t, err)
+	assert.Contains(t, string(cacheConfig), "ref: 0123abcd")
+}
+
+func TestVendirSyncer_verifyCacheDigest(t *testing.T) {
+	t.Parallel()
+
+	app := newLockTestApp(t)
+	dataDir := filepath.Join(app.expandVendirCache("cache"), VendirCacheDataDirName)
+	require.NoError(t, writeFile(filepath.Join(dataDir, "file.yaml"), []byte("a: 1\n")))
+	digest, err := hashDirectory(dataDir)
+	require.NoError(t, err)
+
+	v := NewVendirSyncer(locker.NewLocker())
+	require.NoError(t, v.verifyCacheDigest(app, "cache"), "unlocked caches are not verified")
+
+	v.lockedDigests.Store("cache", digest)
+	require.NoError(t, v.verifyCacheDigest(app, "cache"))
+
+	require.NoError(t, writeFile(filepath.Join(dataDir, "file.yaml"), []byte("a: 2\n")))
+	assert.ErrorContains(t, v.verifyCacheDigest(app, "cache"), "does not match the locked digest")
+}
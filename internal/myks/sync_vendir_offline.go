@@ -0,0 +1,36 @@
+package myks
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrOfflineSourcesMissing is returned in offline mode when sources are missing from the vendir cache.
+var ErrOfflineSourcesMissing = errors.New("sources are missing from the vendir cache, sync them with network access")
+
+// checkOfflineCache returns the content paths that can't be served from the cache,
+// along with an error listing the missing sources.
+func (v *VendirSyncer) checkOfflineCache(a *Application, linksMap map[string]string) (map[string]bool, error) {
+	missingPaths := map[string]bool{}
+	var missing []string
+	for _, contentPath := range slices.Sorted(maps.Keys(linksMap)) {
+		cacheName := linksMap[contentPath]
+		if v.isCachePopulated(a, cacheName) && isCacheDigestValid(a, cacheName) {
+			continue
+		}
+		source := filepath.ToSlash(contentPath)
+		if t, location, version := describeCacheSource(a.expandVendirCache(cacheName), a.cfg.VendirConfigFileName); location != "" {
+			source += fmt.Sprintf(" (%s %s %s)", t, location, version)
+		}
+		missing = append(missing, source)
+		missingPaths[contentPath] = true
+	}
+	if len(missing) > 0 {
+		return missingPaths, fmt.Errorf("%w: %s", ErrOfflineSourcesMissing, strings.Join(missing, ", "))
+	}
+	return missingPaths, nil
+}
@@ -0,0 +1,54 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mykso/myks/internal/locker"
+)
+
+func TestVendirSyncer_doSync_offline(t *testing.T) {
+	t.Parallel()
+
+	g := newSharedCacheTestGlobe(t, "", "cached", "missing")
+	app := g.collectAllApplications()[0]
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("cached"), g.VendirLockFileName), []byte("lock")))
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("missing"), g.VendirConfigFileName), []byte(`apiVersion: vendir.k14s.io/v1alpha1
+kind: Config
+directories:
+  - path: charts/missing
+    contents:
+      - path: .
+        helmChart:
+          name: missing
+          version: 1.0.0
+          repository:
+            url: https://charts.example.com
+`)))
+	staleLink := app.expandVendorPath(filepath.Join("charts", "stale"))
+	require.NoError(t, writeFile(staleLink, []byte("stale")))
+
+	v := NewVendirSyncer(locker.NewLocker())
+	v.Offline = true
+	err := v.doSync(app, "")
+	require.ErrorIs(t, err, ErrOfflineSourcesMissing)
+	assert.Contains(t, err.Error(), "charts/missing (helmChart https://charts.example.com/missing 1.0.0)")
+	assert.NotContains(t, err.Error(), "charts/cached")
+	assert.Zero(t, v.SyncExecuted.Load())
+	assert.FileExists(t, filepath.Join(app.expandVendorPath(filepath.Join("charts", "cached")), "file.yaml"), "cached sources are linked")
+	assert.NoFileExists(t, app.expandVendorPath(filepath.Join("charts", "missing")), "missing sources are not linked")
+	assert.NoFileExists(t, staleLink)
+
+	// Once all entries are cached, they are linked without running vendir, regardless of lazy settings
+	require.NoError(t, writeFile(filepath.Join(app.expandVendirCache("missing"), g.VendirLockFileName), []byte("lock")))
+	require.NoError(t, v.doSync(app, ""))
+	assert.Zero(t, v.SyncExecuted.Load())
+	assert.Equal(t, int64(2), v.SyncSkippedCached.Load())
+	data, err := os.ReadFile(filepath.Join(app.expandVendorPath(filepath.Join("charts", "missing")), "file.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "missing", string(data))
+}
@@ -0,0 +1,372 @@
+package myks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const updateStepName = "update"
+
+// UpdateToLatest is the target version that selects the latest available version of a source.
+const UpdateToLatest = "latest"
+
+// ErrSourceVersionNotFound is returned when the literal defining a source version cannot be found.
+var ErrSourceVersionNotFound = errors.New("source version definition not found")
+
+// SourceUpdate describes a version change of a vendir source.
+type SourceUpdate struct {
+	Env  string `json:"env"`
+	App  string `json:"app"`
+	Path string `json:"path"`
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// File that defines the version, relative to the root directory
+	File string `json:"file"`
+}
+
+// versionEdit is a replacement of a scalar value at a position in a file.
+type versionEdit struct {
+	file   string
+	line   int
+	column int
+	from   string
+	to     string
+}
+
+// UpdateSources rewrites the version of the vendir source matching the given name or path in all initialized applications.
+// The version is rewritten in the vendir or ytt data values file that defines it, preserving comments and formatting.
+// Afterwards, the applications of envAppMap using the edited files are kept initialized, so a subsequent Run processes them only.
+// envAppMap is the selection the globe was initialized with.
+func (g *Globe) UpdateSources(asyncLevel int, envAppMap EnvAppMap, resolver *VersionResolver, source, to string) ([]SourceUpdate, error) {
+	var mu sync.Mutex
+	var updates []SourceUpdate
+	edits := map[versionEdit]bool{}
+
+	err := process(asyncLevel, slices.Values(g.collectAllApplications()), func(app *Application) error {
+		appUpdates, appEdits, err := app.planSourceUpdates(resolver, source, to)
+		if err != nil {
+			return fmt.Errorf("planning update of %s/%s: %w", app.e.ID, app.Name, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		updates = append(updates, appUpdates...)
+		for _, edit := range appEdits {
+			edits[edit] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Several applications usually share the same definition, e.g. in a prototype
+	byFile := map[string][]versionEdit{}
+	for edit := range edits {
+		byFile[edit.file] = append(byFile[edit.file], edit)
+	}
+	for _, file := range slices.Sorted(maps.Keys(byFile)) {
+		if err = applyVersionEdits(file, byFile[file]); err != nil {
+			return nil, err
+		}
+		log.Info().Str("file", file).Msg(g.Msg("Updated source version"))
+	}
+
+	if len(byFile) > 0 {
+		if err = g.selectUpdatedApplications(asyncLevel, envAppMap, slices.Collect(maps.Keys(byFile))); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortFunc(updates, func(x, y SourceUpdate) int {
+		return strings.Compare(x.Env+"\x00"+x.App+"\x00"+x.Path, y.Env+"\x00"+y.App+"\x00"+y.Path)
+	})
+	return updates, nil
+}
+
+// selectUpdatedApplications initializes the selected environments again, so that the edited versions are read,
+// and keeps only the applications whose data files or vendir configs include one of the edited files.
+// Applications outside the selection are not processed, even if they share the edited files.
+func (g *Globe) selectUpdatedApplications(asyncLevel int, envAppMap EnvAppMap, editedFiles []string) error {
+	if err := g.Init(asyncLevel, envAppMap); err != nil {
+		return fmt.Errorf("initializing applications affected by the update: %w", err)
+	}
+	for _, env := range g.environments {
+		var apps []*Application
+		for _, app := range env.Applications {
+			files, err := app.versionDefinitionFiles()
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(files, func(file string) bool { return slices.Contains(editedFiles, file) }) {
+				apps = append(apps, app)
+			}
+		}
+		env.Applications = apps
+		env.initialized = len(apps) > 0
+	}
+	return nil
+}
+
+// planSourceUpdates finds the sources of the application matching the given name or path
+// and locates the definitions of their versions.
+func (a *Application) planSourceUpdates(resolver *VersionResolver, source, to string) ([]SourceUpdate, []versionEdit, error) {
+	vendirConfig, err := a.readRenderedVendirConfig()
+	if errors.Is(err, ErrNoVendirConfig) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := a.versionDefinitionFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var updates []SourceUpdate
+	var edits []versionEdit
+	for _, dir := range vendirConfig.Directories {
+		for _, content := range dir.Contents {
+			path := filepath.ToSlash(filepath.Join(dir.Path, content.Path))
+			var current OutdatedSource
+			var ok bool
+			if to == UpdateToLatest {
+				current, ok = resolver.checkContent(content)
+			} else {
+				current, ok = describeContent(content)
+			}
+			if !ok || (current.Name != source && current.Location != source && path != source) {
+				continue
+			}
+
+			target := to
+			if to == UpdateToLatest {
+				if current.Error != "" {
+					return nil, nil, fmt.Errorf("listing versions of %s: %s", path, current.Error)
+				}
+				if current.Latest == "" {
+					return nil, nil, fmt.Errorf("no semantic versions found for %s", path)
+				}
+				target = current.Latest
+			}
+			if target == current.Current {
+				log.Debug().Str("path", path).Msg(a.Msg(updateStepName, "Source is already at the requested version"))
+				continue
+			}
+
+			edit, err := findVersionDefinition(files, current.Current, []string{current.Name, current.Location})
+			if err != nil {
+				return nil, nil, fmt.Errorf("locating version %s of %s: %w", current.Current, path, err)
+			}
+			// A full image reference can define the version, only its tag is replaced then
+			edit.to = strings.TrimSuffix(edit.from, current.Current) + target
+			edits = append(edits, edit)
+
+			relFile, err := filepath.Rel(a.cfg.RootDir, edit.file)
+			if err != nil {
+				relFile = edit.file
+			}
+			updates = append(updates, SourceUpdate{
+				Env:  a.e.ID,
+				App:  a.Name,
+				Path: path,
+				Name: current.Name,
+				From: current.Current,
+				To:   target,
+				File: filepath.ToSlash(relFile),
+			})
+		}
+	}
+	return updates, edits, nil
+}
+
+// versionDefinitionFiles returns the yaml files that can define source versions of the application,
+// in the order of ytt precedence: data values first, then vendir configs.
+func (a *Application) versionDefinitionFiles() ([]string, error) {
+	vendirFiles, err := a.vendirSourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	// The generated library of the environment is not maintained by users
+	serviceDir := filepath.Join(a.cfg.RootDir, a.cfg.ServiceDirName)
+	var files []string
+	for _, path := range slices.Concat(a.yttDataFiles, vendirFiles) {
+		if rel, err := filepath.Rel(serviceDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if ext := filepath.Ext(file); ext == ".yaml" || ext == ".yml" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("collecting files in %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
+
+// findVersionDefinition finds the scalar defining the given version in the files.
+// Later files take precedence, as they do in ytt. Within a file, a match is
+// disambiguated by a sibling value equal to one of the hints, e.g. the chart name.
+// For image sources, a full image reference ending with the version also matches.
+func findVersionDefinition(files []string, version string, hints []string) (versionEdit, error) {
+	for _, file := range slices.Backward(files) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return versionEdit{}, err
+		}
+		candidates, err := findVersionCandidates(data, version, hints)
+		if err != nil {
+			log.Debug().Err(err).Str("file", file).Msg("Skipping file that can't be parsed as yaml")
+			continue
+		}
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			candidates[0].file = file
+			return candidates[0], nil
+		default:
+			return versionEdit{}, fmt.Errorf("version %s is defined %d times in %s, unable to choose one", version, len(candidates), file)
+		}
+	}
+	return versionEdit{}, ErrSourceVersionNotFound
+}
+
+type versionCandidate struct {
+	edit     versionEdit
+	withHint bool
+}
+
+func findVersionCandidates(data []byte, version string, hints []string) ([]versionEdit, error) {
+	var candidates []versionCandidate
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		candidates = collectVersionCandidates(&doc, version, hints, candidates)
+	}
+
+	// Prefer candidates next to a hint, e.g. the chart name
+	if slices.ContainsFunc(candidates, func(c versionCandidate) bool { return c.withHint }) {
+		candidates = slices.DeleteFunc(candidates, func(c versionCandidate) bool { return !c.withHint })
+	}
+	edits := make([]versionEdit, 0, len(candidates))
+	for _, c := range candidates {
+		edits = append(edits, c.edit)
+	}
+	return edits, nil
+}
+
+func collectVersionCandidates(node *yaml.Node, version string, hints []string, candidates []versionCandidate) []versionCandidate {
+	if node.Kind == yaml.MappingNode {
+		withHint := false
+		for i := 1; i < len(node.Content); i += 2 {
+			value := node.Content[i]
+			if value.Kind == yaml.ScalarNode && value.Value != version && slices.Contains(hints, value.Value) {
+				withHint = true
+			}
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			value := node.Content[i]
+			if value.Kind == yaml.ScalarNode && matchesVersion(value.Value, version) {
+				candidates = append(candidates, versionCandidate{
+					edit:     versionEdit{line: value.Line, column: value.Column, from: value.Value},
+					withHint: withHint,
+				})
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if node.Kind == yaml.MappingNode && child.Kind == yaml.ScalarNode {
+			continue
+		}
+		candidates = collectVersionCandidates(child, version, hints, candidates)
+	}
+	return candidates
+}
+
+// matchesVersion reports whether a scalar value defines the version, either literally or as an image tag.
+func matchesVersion(value, version string) bool {
+	return value == version || (version != "" && strings.HasSuffix(value, ":"+version) && !strings.Contains(value, " "))
+}
+
+// applyVersionEdits replaces the scalar values in the file, keeping the rest of the file intact.
+func applyVersionEdits(file string, edits []versionEdit) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	// Replace from the end of the file, so the positions of earlier edits stay valid
+	slices.SortFunc(edits, func(x, y versionEdit) int {
+		if x.line != y.line {
+			return y.line - x.line
+		}
+		return y.column - x.column
+	})
+	for i, edit := range edits {
+		if i > 0 && edit.line == edits[i-1].line && edit.column == edits[i-1].column {
+			if edit.to != edits[i-1].to {
+				return fmt.Errorf("conflicting updates of %s:%d: %s and %s", file, edit.line, edit.to, edits[i-1].to)
+			}
+			continue
+		}
+		if data, err = replaceScalarAt(data, edit); err != nil {
+			return fmt.Errorf("updating %s: %w", file, err)
+		}
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, info.Mode().Perm())
+}
+
+// replaceScalarAt replaces a plain or quoted scalar starting at the 1-based line and column.
+func replaceScalarAt(data []byte, edit versionEdit) ([]byte, error) {
+	offset := 0
+	for line := 1; line < edit.line; line++ {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d is out of range", edit.line)
+		}
+		offset += i + 1
+	}
+	// Columns are counted in characters
+	for column := 1; column < edit.column && offset < len(data); column++ {
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	if offset < len(data) && (data[offset] == '"' || data[offset] == '\'') {
+		offset++
+	}
+	if !bytes.HasPrefix(data[offset:], []byte(edit.from)) {
+		return nil, fmt.Errorf("expected %q at line %d, column %d", edit.from, edit.line, edit.column)
+	}
+	return slices.Concat(data[:offset], []byte(edit.to), data[offset+len(edit.from):]), nil
+}
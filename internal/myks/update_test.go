@@ -0,0 +1,137 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindVersionDefinition(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	protoData := writeTestFile(t, filepath.Join(dir, "prototype", "vendir-data.ytt.yaml"), `#@data/values-schema
+---
+application:
+  #! renovate: datasource=helm
+  name: httpbingo
+  url: https://estahn.github.io/charts
+  version: 0.1.0
+`)
+	protoConfig := writeTestFile(t, filepath.Join(dir, "prototype", "base.ytt.yaml"), `#@ load("@ytt:data", "data")
+---
+apiVersion: vendir.k14s.io/v1alpha1
+kind: Config
+directories:
+  - path: charts/httpbingo
+    contents:
+      - path: .
+        helmChart:
+          name: httpbingo
+          version: #@ data.values.application.version
+`)
+	envData := writeTestFile(t, filepath.Join(dir, "env", "vendir-data.ytt.yaml"), `#@data/values
+---
+application:
+  version: "0.1.1" #! pinned for dev
+`)
+
+	edit, err := findVersionDefinition([]string{protoData, protoConfig}, "0.1.0", []string{"httpbingo"})
+	require.NoError(t, err)
+	assert.Equal(t, versionEdit{file: protoData, line: 7, column: 12, from: "0.1.0"}, edit)
+
+	edit, err = findVersionDefinition([]string{protoData, protoConfig, envData}, "0.1.1", []string{"httpbingo"})
+	require.NoError(t, err)
+	assert.Equal(t, versionEdit{file: envData, line: 4, column: 12, from: "0.1.1"}, edit)
+
+	_, err = findVersionDefinition([]string{protoData, protoConfig}, "9.9.9", nil)
+	assert.ErrorIs(t, err, ErrSourceVersionNotFound)
+}
+
+func TestFindVersionCandidates(t *testing.T) {
+	t.Parallel()
+
+	multiDoc := `---
+other:
+  version: 1.0.0
+---
+directories:
+  - contents:
+      - helmChart:
+          name: nginx
+          version: 1.0.0
+      - image:
+          url: ghcr.io/org/config:1.0.0
+`
+	edits, err := findVersionCandidates([]byte(multiDoc), "1.0.0", []string{"nginx"})
+	require.NoError(t, err)
+	assert.Equal(t, []versionEdit{{line: 9, column: 20, from: "1.0.0"}}, edits, "a match next to a hint is preferred")
+
+	edits, err = findVersionCandidates([]byte(multiDoc), "1.0.0", []string{"unknown"})
+	require.NoError(t, err)
+	assert.Len(t, edits, 3, "all matches are returned without a hint")
+
+	edits, err = findVersionCandidates([]byte(multiDoc), "1.0.0", []string{"ghcr.io/org/config"})
+	require.NoError(t, err)
+	assert.Len(t, edits, 3, "the image reference itself is not a hint")
+
+	_, err = findVersionCandidates([]byte("a: [b"), "1.0.0", nil)
+	assert.Error(t, err)
+}
+
+func TestApplyVersionEdits(t *testing.T) {
+	t.Parallel()
+
+	file := writeTestFile(t, filepath.Join(t.TempDir(), "data.yaml"), `#@data/values
+---
+#! The chart version
+chart:
+  version: "1.0.0" #! keep this comment
+image: 'ghcr.io/org/config:1.0.0'
+`)
+	edits := []versionEdit{
+		{file: file, line: 5, column: 12, from: "1.0.0", to: "1.10.0"},
+		{file: file, line: 6, column: 8, from: "ghcr.io/org/config:1.0.0", to: "ghcr.io/org/config:2.0.0"},
+		// The same definition is shared by several applications
+		{file: file, line: 5, column: 12, from: "1.0.0", to: "1.10.0"},
+	}
+	require.NoError(t, applyVersionEdits(file, edits))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `#@data/values
+---
+#! The chart version
+chart:
+  version: "1.10.0" #! keep this comment
+image: 'ghcr.io/org/config:2.0.0'
+`, string(data))
+
+	err = applyVersionEdits(file, []versionEdit{
+		{file: file, line: 5, column: 12, from: "1.10.0", to: "2.0.0"},
+		{file: file, line: 5, column: 12, from: "1.10.0", to: "3.0.0"},
+	})
+	assert.ErrorContains(t, err, "conflicting updates")
+
+	err = applyVersionEdits(file, []versionEdit{{file: file, line: 5, column: 12, from: "9.9.9", to: "2.0.0"}})
+	assert.ErrorContains(t, err, `expected "9.9.9"`)
+}
+
+func TestApplication_versionDefinitionFiles(t *testing.T) {
+	t.Parallel()
+
+	g := createGlobe(t)
+	g.RootDir = t.TempDir()
+	env := &Environment{Dir: filepath.Join(g.RootDir, "envs", "env1"), g: g, cfg: &g.Config, ID: "env1"}
+	app := &Application{Name: "app", e: env, cfg: &g.Config, Prototype: filepath.Join(g.RootDir, "prototypes", "app")}
+	apiLib := writeTestFile(t, filepath.Join(env.getYttLibAPIDir(), "data.lib.yaml"), "version: 1.0.0")
+	envData := writeTestFile(t, filepath.Join(env.Dir, "env-data.ytt.yaml"), "version: 1.0.0")
+	app.yttDataFiles = []string{filepath.Dir(apiLib), envData}
+
+	files, err := app.versionDefinitionFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{envData}, files, "the generated library of the environment is skipped")
+}
@@ -2,66 +2,32 @@ package myks
 
 import (
 	"bytes"
+	"cmp"
 	_ "embed"
+	"fmt"
 	"os"
-	"regexp"
+	"path/filepath"
 	"sort"
-	"strings"
 
 	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
 )
 
 //go:embed templates/vendir/secret.ytt.yaml
 var vendirSecretTemplate []byte
 
-func (v *VendirSyncer) collectVendirSecrets(g *Globe) map[string]*VendirCredentials {
-	vendirCredentials := make(map[string]*VendirCredentials)
+//go:embed templates/vendir/secret-schema.ytt.yaml
+var vendirSecretSchema []byte
 
-	usrRgx := regexp.MustCompile("^" + g.VendirSecretEnvPrefix + "(.+)_USERNAME=(.*)$")
-	pswRgx := regexp.MustCompile("^" + g.VendirSecretEnvPrefix + "(.+)_PASSWORD=(.*)$")
-
-	envvars := os.Environ()
-	// Sort envvars to produce deterministic output for testing
-	sort.Strings(envvars)
-	for _, envPair := range envvars {
-		if usrRgx.MatchString(envPair) {
-			match := usrRgx.FindStringSubmatch(envPair)
-			secretName := strings.ToLower(match[1])
-			username := match[2]
-			if vendirCredentials[secretName] == nil {
-				vendirCredentials[secretName] = &VendirCredentials{}
-			}
-			vendirCredentials[secretName].Username = username
-		} else if pswRgx.MatchString(envPair) {
-			match := pswRgx.FindStringSubmatch(envPair)
-			secretName := strings.ToLower(match[1])
-			password := match[2]
-			if vendirCredentials[secretName] == nil {
-				vendirCredentials[secretName] = &VendirCredentials{}
-			}
-			vendirCredentials[secretName].Password = password
-		}
-	}
-
-	for secretName, credentials := range vendirCredentials {
-		if credentials.Username == "" || credentials.Password == "" {
-			log.Warn().Msg("Incomplete credentials for secret: " + secretName)
-			delete(vendirCredentials, secretName)
-		}
-	}
-
-	var secretNames []string
-	for secretName := range vendirCredentials {
-		secretNames = append(secretNames, secretName)
-	}
-	log.Debug().Msg(msgWithSteps("sync", v.Ident(), "Found vendir secrets: "+strings.Join(secretNames, ", ")))
-
-	return vendirCredentials
-}
+// helmTokenUsername is the user name passed along with a token to helm chart repositories if none is configured.
+const helmTokenUsername = "token"
 
 func (v *VendirSyncer) GenerateSecrets(g *Globe) (string, error) {
 	log.Debug().Msg(msgWithSteps("sync", v.Ident(), "Generating Secrets"))
-	vendirCredentials := v.collectVendirSecrets(g)
+	vendirCredentials, err := v.collectVendirSecrets(g)
+	if err != nil {
+		return "", err
+	}
 
 	// sort secret names to produce deterministic output for testing
 	var secretNames []string
@@ -70,24 +36,77 @@ func (v *VendirSyncer) GenerateSecrets(g *Globe) (string, error) {
 	}
 	sort.Strings(secretNames)
 
-	var secretYamls string
+	var secretYamls, helmSecretYamls string
 	for _, secretName := range secretNames {
 		credentials := vendirCredentials[secretName]
-		secretYaml, err := v.generateVendirSecretYaml(g, secretName, credentials.Username, credentials.Password)
+		secretYaml, err := v.generateVendirSecretYaml(g, secretName, credentials)
 		if err != nil {
 			return secretYamls, err
 		}
 		secretYamls += "---\n" + secretYaml
+
+		helmCredentials := credentials.forHelm()
+		if helmCredentials == nil {
+			continue
+		}
+		helmSecretYaml, err := v.generateVendirSecretYaml(g, secretName, helmCredentials)
+		if err != nil {
+			return secretYamls, err
+		}
+		helmSecretYamls += "---\n" + helmSecretYaml
 	}
+	v.helmSecrets = helmSecretYamls
 
 	return secretYamls, nil
 }
 
-func (v *VendirSyncer) generateVendirSecretYaml(g *Globe, secretName, username, password string) (string, error) {
-	res, err := runYttWithFilesAndStdin(
+// forHelm returns the credentials supported by helm chart sources of vendir, which only accept a username and
+// a password. A token is passed as the password. Returns nil if there are no such credentials.
+func (c *VendirCredentials) forHelm() *VendirCredentials {
+	switch {
+	case c.Username != "" && c.Password != "":
+		return &VendirCredentials{Username: c.Username, Password: c.Password}
+	case c.Token != "":
+		return &VendirCredentials{Username: cmp.Or(c.Username, helmTokenUsername), Password: c.Token}
+	}
+	return nil
+}
+
+func (v *VendirSyncer) generateVendirSecretYaml(g *Globe, secretName string, credentials *VendirCredentials) (string, error) {
+	// Credentials are passed on stdin, so they don't show up in the process list or in the logs
+	dataValues, err := yaml.Marshal(map[string]string{
+		"secret_name":     secretName,
+		"username":        credentials.Username,
+		"password":        credentials.Password,
+		"token":           credentials.Token,
+		"ssh_private_key": credentials.SSHPrivateKey,
+		"ssh_known_hosts": credentials.SSHKnownHosts,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshaling vendir secret data values: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "myks-vendir-secret-")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warn().Err(err).Str("dir", tmpDir).Msg("Unable to remove temporary directory")
+		}
+	}()
+	if err := writeFile(filepath.Join(tmpDir, "secret.ytt.yaml"), vendirSecretTemplate); err != nil {
+		return "", fmt.Errorf("writing vendir secret template: %w", err)
+	}
+	if err := writeFile(filepath.Join(tmpDir, "schema.ytt.yaml"), vendirSecretSchema); err != nil {
+		return "", fmt.Errorf("writing vendir secret schema: %w", err)
+	}
+
+	res, err := runCmd(
 		v.getStepName(),
-		nil,
-		bytes.NewReader(vendirSecretTemplate),
+		myksFullPath(),
+		bytes.NewReader(dataValues),
+		[]string{"ytt", "--file=" + tmpDir, "--data-values-file=-"},
 		func(name string, err error, stderr string, args []string) {
 			cmd := msgRunCmd("render vendir secret yaml", name, args)
 			if err != nil {
@@ -97,9 +116,6 @@ func (v *VendirSyncer) generateVendirSecretYaml(g *Globe, secretName, username,
 				log.Debug().Msg(g.Msg(stderr))
 			}
 		},
-		"--data-value=secret_name="+secretName,
-		"--data-value=username="+username,
-		"--data-value=password="+password,
 	)
 	if err != nil {
 		return "", err
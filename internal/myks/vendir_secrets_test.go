@@ -71,7 +71,10 @@ func Test_collectVendirSecrets(t *testing.T) {
 				t.Setenv(k, v)
 			}
 			vendir := VendirSyncer{}
-			got := vendir.collectVendirSecrets(New("."))
+			got, err := vendir.collectVendirSecrets(New("."))
+			if err != nil {
+				t.Fatal(err)
+			}
 			assertEqual(t, got, tt.want)
 		})
 	}
@@ -147,9 +150,8 @@ func Test_generateVendirSecretYamls(t *testing.T) {
 
 func Test_generateVendirSecretYaml(t *testing.T) {
 	type args struct {
-		secretName string
-		username   string
-		password   string
+		secretName  string
+		credentials VendirCredentials
 	}
 	tests := []struct {
 		name    string
@@ -157,12 +159,14 @@ func Test_generateVendirSecretYaml(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{"happy args", args{"artifactory", "username", "password"}, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: artifactory\ndata:\n  username: dXNlcm5hbWU=\n  password: cGFzc3dvcmQ=\n", false},
+		{"happy args", args{"artifactory", VendirCredentials{Username: "username", Password: "password"}}, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: artifactory\ndata:\n  username: dXNlcm5hbWU=\n  password: cGFzc3dvcmQ=\n", false},
+		{"token", args{"registry", VendirCredentials{Token: "tok"}}, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: registry\ndata:\n  token: dG9r\n", false},
+		{"ssh key", args{"git", VendirCredentials{SSHPrivateKey: "key", SSHKnownHosts: "hosts"}}, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: git\ndata:\n  ssh-privatekey: a2V5\n  ssh-knownhosts: aG9zdHM=\n", false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			vendir := VendirSyncer{}
-			got, err := vendir.generateVendirSecretYaml(New("."), tt.args.secretName, tt.args.username, tt.args.password)
+			got, err := vendir.generateVendirSecretYaml(New("."), tt.args.secretName, &tt.args.credentials)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("generateVendirSecretYaml() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -171,3 +175,24 @@ func Test_generateVendirSecretYaml(t *testing.T) {
 		})
 	}
 }
+
+func TestVendirCredentials_forHelm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		credentials VendirCredentials
+		want        *VendirCredentials
+	}{
+		{"basic auth", VendirCredentials{Username: "user", Password: "pass", Token: "tok"}, &VendirCredentials{Username: "user", Password: "pass"}},
+		{"token", VendirCredentials{Token: "tok"}, &VendirCredentials{Username: helmTokenUsername, Password: "tok"}},
+		{"token with username", VendirCredentials{Username: "robot", Token: "tok"}, &VendirCredentials{Username: "robot", Password: "tok"}},
+		{"ssh key", VendirCredentials{SSHPrivateKey: "key"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tt.credentials.forHelm(), tt.want)
+		})
+	}
+}
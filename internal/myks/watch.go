@@ -0,0 +1,330 @@
+package myks
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// WatchOptions configures Globe.Watch.
+type WatchOptions struct {
+	// Number of applications processed in parallel, 0 means no limit
+	AsyncLevel int
+	// Quiet period after the last file change before re-rendering
+	Debounce time.Duration
+	// Environments and applications to re-render, nil means all
+	Scope EnvAppMap
+	// Sync external sources before rendering
+	Sync bool
+	// Called after every re-render
+	OnBatch func(WatchBatch)
+}
+
+// WatchBatch is the result of re-rendering the applications affected by a burst of file changes.
+type WatchBatch struct {
+	Changed   ChangedFiles
+	Selection EnvAppMap
+	Apps      []WatchAppStatus
+	// Error not attributed to a single application, e.g. a failed environment initialization
+	Err error
+}
+
+// WatchAppStatus is the result of re-rendering a single application.
+type WatchAppStatus struct {
+	EnvID    string
+	App      string
+	Duration time.Duration
+	Err      error
+}
+
+// Watch re-renders the applications affected by changes of the project files until the context is canceled.
+// Changes are collected until no file changes for the debounce period, and classified by Smart Mode.
+func (g *Globe) Watch(ctx context.Context, opts WatchOptions) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if _, err := g.compileSmartModeRules(); err != nil {
+		return err
+	}
+	if err := g.collectWatchEnvironments(); err != nil {
+		return err
+	}
+	if _, err := g.addWatchDirs(watcher, g.RootDir); err != nil {
+		return err
+	}
+	log.Info().Str("root", g.RootDir).Msg(g.Msg("Watching for changes"))
+
+	scope := g.AddBaseDirToEnvAppMap(opts.Scope)
+	if opts.Scope == nil {
+		scope = nil
+	}
+	pending := ChangedFiles{}
+	rescan := false
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warn().Err(err).Msg(g.Msg("File watcher error"))
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			changed, dirChanged := g.watchEventChanges(watcher, event)
+			for path, status := range changed {
+				pending[path] = mergeChangeStatus(pending[path], status)
+			}
+			rescan = rescan || dirChanged
+			if len(pending) > 0 {
+				timer.Reset(opts.Debounce)
+			}
+		case <-timer.C:
+			batch := g.renderWatchBatch(pending, scope, rescan, opts)
+			if opts.OnBatch != nil {
+				opts.OnBatch(batch)
+			}
+			pending = ChangedFiles{}
+			rescan = false
+		}
+	}
+}
+
+// collectWatchEnvironments collects all environments and their applications for the Smart Mode classifier.
+func (g *Globe) collectWatchEnvironments() error {
+	g.environments = map[string]*Environment{}
+	_ = g.collectEnvironments(nil)
+	err := process(0, maps.Values(g.environments), func(env *Environment) error {
+		return env.initEnvData()
+	})
+	if err != nil {
+		return fmt.Errorf("collecting environments: %w", err)
+	}
+	return nil
+}
+
+// addWatchDirs watches a directory and its subdirectories, ignoring paths that are not rendering inputs.
+// Returns the files found, so that files created along with a new directory are not missed.
+func (g *Globe) addWatchDirs(watcher *fsnotify.Watcher, root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(g.RootDir, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && g.isIgnoredInputPath(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, rel)
+			return nil
+		}
+		return watcher.Add(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("watching %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// watchEventChanges converts a file system event to changed files in git status notation.
+// Also reports whether a directory was created or removed, which may add or remove environments.
+func (g *Globe) watchEventChanges(watcher *fsnotify.Watcher, event fsnotify.Event) (ChangedFiles, bool) {
+	rel, err := filepath.Rel(g.RootDir, event.Name)
+	if err != nil || g.isIgnoredWatchPath(rel) {
+		return nil, false
+	}
+	changed := ChangedFiles{}
+	switch {
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		changed[g.GitPathPrefix+filepath.ToSlash(rel)] = "D"
+		return changed, slices.Contains(watcher.WatchList(), event.Name)
+	case event.Has(fsnotify.Create):
+		if ok, err := isDir(event.Name); err == nil && ok {
+			files, err := g.addWatchDirs(watcher, event.Name)
+			if err != nil {
+				log.Warn().Err(err).Msg(g.Msg("Unable to watch a new directory"))
+			}
+			for _, file := range files {
+				changed[g.GitPathPrefix+filepath.ToSlash(file)] = "A"
+			}
+			return changed, true
+		}
+		changed[g.GitPathPrefix+filepath.ToSlash(rel)] = "A"
+	case event.Has(fsnotify.Write):
+		changed[g.GitPathPrefix+filepath.ToSlash(rel)] = "M"
+	}
+	return changed, false
+}
+
+// isIgnoredWatchPath extends isIgnoredInputPath with backup files of editors.
+func (g *Globe) isIgnoredWatchPath(rel string) bool {
+	return rel == "." || g.isIgnoredInputPath(rel) || strings.HasSuffix(rel, "~")
+}
+
+// mergeChangeStatus combines the status of a file changed several times within a batch.
+func mergeChangeStatus(previous, next string) string {
+	switch {
+	case previous == "":
+		return next
+	case previous == "D" && next != "D":
+		// Editors often save by replacing the file
+		return "M"
+	case previous == "A" && next == "M":
+		return "A"
+	}
+	return next
+}
+
+// isEnvDataChange reports whether the changed files include environment data files, which define the
+// applications of environments.
+func (g *Globe) isEnvDataChange(changedFiles ChangedFiles) bool {
+	for path := range changedFiles {
+		if ok, _ := filepath.Match(g.EnvironmentDataFileName, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// renderWatchBatch selects and re-renders the applications affected by the changed files.
+func (g *Globe) renderWatchBatch(changedFiles ChangedFiles, scope EnvAppMap, rescan bool, opts WatchOptions) WatchBatch {
+	batch := WatchBatch{Changed: changedFiles}
+	if rescan || g.isEnvDataChange(changedFiles) {
+		if err := g.collectWatchEnvironments(); err != nil {
+			batch.Err = err
+			return batch
+		}
+	}
+
+	batch.Selection = intersectEnvAppMaps(g.runSmartMode(changedFiles), scope)
+	if len(batch.Selection) == 0 {
+		return batch
+	}
+
+	// A separate globe keeps the classifier environments intact and only initializes the selected ones
+	run := g.cloneForRun()
+	var mu sync.Mutex
+	run.appProcessed = func(app *Application, elapsed time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		batch.Apps = append(batch.Apps, WatchAppStatus{EnvID: app.e.ID, App: app.Name, Duration: elapsed, Err: err})
+	}
+	if err := run.Init(opts.AsyncLevel, batch.Selection); err != nil {
+		batch.Err = fmt.Errorf("unable to initialize myks' globe: %w", err)
+		return batch
+	}
+	err := run.Run(opts.AsyncLevel, opts.Sync, true)
+	slices.SortFunc(batch.Apps, func(a, b WatchAppStatus) int {
+		return cmp.Or(cmp.Compare(a.EnvID, b.EnvID), cmp.Compare(a.App, b.App))
+	})
+	if err != nil {
+		if !slices.ContainsFunc(batch.Apps, func(s WatchAppStatus) bool { return s.Err != nil }) {
+			batch.Err = err
+		}
+		return batch
+	}
+
+	// Like a Smart Mode render, changes outside the scope are not covered
+	if scope == nil {
+		if g.smartModeDeletions, err = g.detectDeletedApplications(changedFiles); err != nil {
+			batch.Err = err
+			return batch
+		}
+		g.CleanupSmartModeDeletions(false)
+		if !g.WithGit {
+			if err := g.UpdateChangeManifest(changedFiles); err != nil {
+				log.Warn().Err(err).Msg(g.Msg("Unable to save the Smart Mode change manifest"))
+			}
+		}
+	}
+	return batch
+}
+
+// cloneForRun returns a globe with the same configuration and no collected environments.
+func (g *Globe) cloneForRun() *Globe {
+	c := *g
+	c.environments = map[string]*Environment{}
+	c.smartModeDeletions = nil
+	c.smartModeReasons = nil
+	c.appProcessed = nil
+	return &c
+}
+
+// intersectEnvAppMaps returns the environments and applications selected by both maps, nil meaning everything.
+// Nested environment paths are matched, e.g. `envs/prod` in one map selects `envs/prod/eu` of the other.
+func intersectEnvAppMaps(a, b EnvAppMap) EnvAppMap {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	isUnder := func(path, root string) bool {
+		path, root = filepath.Clean(path), filepath.Clean(root)
+		return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+	}
+	result := EnvAppMap{}
+	add := func(env string, apps []string) {
+		existing, ok := result[env]
+		switch {
+		case !ok:
+			result[env] = apps
+		case existing == nil || apps == nil:
+			result[env] = nil
+		default:
+			result[env] = unique(append(existing, apps...))
+		}
+	}
+	for envA, appsA := range a {
+		for envB, appsB := range b {
+			env := envA
+			if isUnder(envB, envA) {
+				env = envB
+			} else if !isUnder(envA, envB) {
+				continue
+			}
+			switch {
+			case appsA == nil:
+				add(env, appsB)
+			case appsB == nil:
+				add(env, appsA)
+			default:
+				var apps []string
+				for _, app := range appsA {
+					if slices.Contains(appsB, app) {
+						apps = append(apps, app)
+					}
+				}
+				if len(apps) > 0 {
+					add(env, apps)
+				}
+			}
+		}
+	}
+	pruneNestedEnvAppMap(result)
+	return result
+}
@@ -0,0 +1,98 @@
+package myks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_intersectEnvAppMaps(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b EnvAppMap
+		want EnvAppMap
+	}{
+		{"nil scope", EnvAppMap{"envs/a": {"app1"}}, nil, EnvAppMap{"envs/a": {"app1"}}},
+		{"all selected", EnvAppMap{"envs": nil}, EnvAppMap{"envs/a": {"app1"}}, EnvAppMap{"envs/a": {"app1"}}},
+		{"nested selection", EnvAppMap{"envs/a/eu": {"app1", "app2"}}, EnvAppMap{"envs/a": nil}, EnvAppMap{"envs/a/eu": {"app1", "app2"}}},
+		{"common apps", EnvAppMap{"envs/a": {"app1", "app2"}}, EnvAppMap{"envs/a": {"app2", "app3"}}, EnvAppMap{"envs/a": {"app2"}}},
+		{"no common apps", EnvAppMap{"envs/a": {"app1"}}, EnvAppMap{"envs/a": {"app2"}}, EnvAppMap{}},
+		{"other environment", EnvAppMap{"envs/a": nil}, EnvAppMap{"envs/b": nil}, EnvAppMap{}},
+		{"similar prefix", EnvAppMap{"envs/a": nil}, EnvAppMap{"envs/ab": nil}, EnvAppMap{}},
+		{
+			"several environments",
+			EnvAppMap{"envs": {"app1"}, "envs/b": nil},
+			EnvAppMap{"envs/a": nil, "envs/b": {"app2"}},
+			EnvAppMap{"envs/a": {"app1"}, "envs/b": {"app2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := intersectEnvAppMaps(tt.a, tt.b)
+			for env := range got {
+				assert.ElementsMatch(t, tt.want[env], got[env], env)
+			}
+			assert.Len(t, got, len(tt.want))
+		})
+	}
+}
+
+func Test_mergeChangeStatus(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "A", mergeChangeStatus("", "A"))
+	assert.Equal(t, "A", mergeChangeStatus("A", "M"))
+	assert.Equal(t, "M", mergeChangeStatus("D", "A"))
+	assert.Equal(t, "D", mergeChangeStatus("M", "D"))
+	assert.Equal(t, "D", mergeChangeStatus("A", "D"))
+}
+
+func TestGlobe_watchEventChanges(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	g.RootDir = t.TempDir()
+	g.GitPathPrefix = "project/"
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = watcher.Close() })
+	path := func(name string) string { return filepath.Join(g.RootDir, name) }
+
+	changed, dirChanged := g.watchEventChanges(watcher, fsnotify.Event{Name: path("envs/a/env-data.ytt.yaml"), Op: fsnotify.Write})
+	assert.Equal(t, ChangedFiles{"project/envs/a/env-data.ytt.yaml": "M"}, changed)
+	assert.False(t, dirChanged)
+
+	changed, _ = g.watchEventChanges(watcher, fsnotify.Event{Name: path("prototypes/app/app-data.ytt.yaml"), Op: fsnotify.Rename})
+	assert.Equal(t, ChangedFiles{"project/prototypes/app/app-data.ytt.yaml": "D"}, changed)
+
+	for _, ignored := range []string{"rendered/envs/a/app/manifest.yaml", ".myks/smart-mode-manifest.yaml", "envs/.env-data.ytt.yaml.swp", "envs/a/app.yaml~"} {
+		changed, _ = g.watchEventChanges(watcher, fsnotify.Event{Name: path(ignored), Op: fsnotify.Write})
+		assert.Empty(t, changed, ignored)
+	}
+
+	// Files created along with a directory are reported, and the directory is watched
+	require.NoError(t, writeFile(path("envs/b/env-data.ytt.yaml"), []byte("env")))
+	require.NoError(t, os.MkdirAll(path("envs/b/.hidden"), 0o750))
+	changed, dirChanged = g.watchEventChanges(watcher, fsnotify.Event{Name: path("envs/b"), Op: fsnotify.Create})
+	assert.Equal(t, ChangedFiles{"project/envs/b/env-data.ytt.yaml": "A"}, changed)
+	assert.True(t, dirChanged)
+	assert.Equal(t, []string{path("envs/b")}, watcher.WatchList())
+
+	_, dirChanged = g.watchEventChanges(watcher, fsnotify.Event{Name: path("envs/b"), Op: fsnotify.Remove})
+	assert.True(t, dirChanged, "removing a watched directory may remove environments")
+}
+
+func TestGlobe_isEnvDataChange(t *testing.T) {
+	t.Parallel()
+
+	g := NewWithDefaults()
+	assert.True(t, g.isEnvDataChange(ChangedFiles{"envs/a/env-data.ytt.yaml": "M"}))
+	assert.False(t, g.isEnvDataChange(ChangedFiles{"envs/a/_apps/app/app-data.ytt.yaml": "M"}))
+}
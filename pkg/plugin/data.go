@@ -0,0 +1,164 @@
+package plugin
+
+// DataValues are the data values of an application or environment, as defined by the myks data schema.
+// Keys added to the schema by users are ignored, use Context.DecodeDataValues to access them.
+type DataValues struct {
+	// Application-specific data
+	Application map[string]any `yaml:"application"`
+	ArgoCD      ArgoCD         `yaml:"argocd"`
+	Environment Environment    `yaml:"environment"`
+	Helm        Helm           `yaml:"helm"`
+	Kbld        Kbld           `yaml:"kbld"`
+	YttPkg      YttPkg         `yaml:"yttPkg"`
+	Render      Render         `yaml:"render"`
+	Myks        Myks           `yaml:"myks"`
+}
+
+// ArgoCD configures the ArgoCD resources rendered by myks.
+type ArgoCD struct {
+	Enabled   bool          `yaml:"enabled"`
+	Namespace string        `yaml:"namespace"`
+	App       ArgoCDApp     `yaml:"app"`
+	Env       ArgoCDEnv     `yaml:"env"`
+	Project   ArgoCDProject `yaml:"project"`
+}
+
+// ArgoCDApp configures the ArgoCD Application resource.
+type ArgoCDApp struct {
+	Name        string              `yaml:"name"`
+	Prefix      string              `yaml:"prefix"`
+	Finalizers  []string            `yaml:"finalizers"`
+	Destination ArgoCDDestination   `yaml:"destination"`
+	Source      ArgoCDSource        `yaml:"source"`
+	SyncPolicy  ArgoCDAppSyncPolicy `yaml:"syncPolicy"`
+}
+
+// ArgoCDDestination is the destination of an ArgoCD Application or AppProject.
+type ArgoCDDestination struct {
+	Name      string `yaml:"name"`
+	Server    string `yaml:"server"`
+	Namespace string `yaml:"namespace"`
+}
+
+// ArgoCDSource is the source of an ArgoCD Application.
+type ArgoCDSource struct {
+	Path           string `yaml:"path"`
+	Plugin         any    `yaml:"plugin"`
+	RepoURL        string `yaml:"repoURL"`
+	TargetRevision string `yaml:"targetRevision"`
+}
+
+// ArgoCDAppSyncPolicy is the sync policy of an ArgoCD Application.
+type ArgoCDAppSyncPolicy struct {
+	Prune    bool `yaml:"prune"`
+	SelfHeal bool `yaml:"selfHeal"`
+	// Sync options, nil values are ignored
+	SyncOptions map[string]*string `yaml:"syncOptions"`
+}
+
+// ArgoCDEnv configures the ArgoCD cluster secret of the environment.
+type ArgoCDEnv struct {
+	Name           string `yaml:"name"`
+	Prefix         string `yaml:"prefix"`
+	GenerateSecret bool   `yaml:"generateSecret"`
+}
+
+// ArgoCDProject configures the ArgoCD AppProject resource.
+type ArgoCDProject struct {
+	Enabled     bool              `yaml:"enabled"`
+	Name        string            `yaml:"name"`
+	Prefix      string            `yaml:"prefix"`
+	Destination ArgoCDDestination `yaml:"destination"`
+}
+
+// Environment describes the environment and its applications.
+type Environment struct {
+	ID           string                   `yaml:"id"`
+	Applications []EnvironmentApplication `yaml:"applications"`
+}
+
+// EnvironmentApplication is an application deployed in the environment.
+type EnvironmentApplication struct {
+	Proto string `yaml:"proto"`
+	// Name of the application, the name of the prototype if empty
+	Name string `yaml:"name"`
+}
+
+// Helm configures the step that renders Helm charts.
+type Helm struct {
+	BuildDependencies bool        `yaml:"buildDependencies"`
+	Capabilities      []string    `yaml:"capabilities"`
+	IncludeCRDs       bool        `yaml:"includeCRDs"`
+	KubeVersion       string      `yaml:"kubeVersion"`
+	Namespace         string      `yaml:"namespace"`
+	Charts            []HelmChart `yaml:"charts"`
+}
+
+// HelmChart overrides the Helm configuration for a single chart.
+type HelmChart struct {
+	ReleaseName       string `yaml:"releaseName"`
+	BuildDependencies *bool  `yaml:"buildDependencies"`
+	IncludeCRDs       *bool  `yaml:"includeCRDs"`
+	Name              string `yaml:"name"`
+	Namespace         string `yaml:"namespace"`
+	Path              string `yaml:"path"`
+}
+
+// Kbld configures the step that manages image references.
+type Kbld struct {
+	Enabled          bool           `yaml:"enabled"`
+	ImagesAnnotation bool           `yaml:"imagesAnnotation"`
+	Cache            bool           `yaml:"cache"`
+	Overrides        []KbldOverride `yaml:"overrides"`
+}
+
+// KbldOverride replaces parts of matching image references.
+type KbldOverride struct {
+	Match   KbldImageRef `yaml:"match"`
+	Replace KbldImageRef `yaml:"replace"`
+}
+
+// KbldImageRef holds the parts of an image reference.
+type KbldImageRef struct {
+	Registry   string `yaml:"registry"`
+	Repository string `yaml:"repository"`
+	Tag        string `yaml:"tag"`
+}
+
+// YttPkg configures the step that renders ytt packages.
+type YttPkg struct {
+	Dirs []string `yaml:"dirs"`
+}
+
+// Render configures the render step.
+type Render struct {
+	IncludeNamespace bool `yaml:"includeNamespace"`
+}
+
+// Myks holds the runtime data set by myks.
+type Myks struct {
+	GitRepoBranch string      `yaml:"gitRepoBranch"`
+	GitRepoURL    string      `yaml:"gitRepoUrl"`
+	Context       MyksContext `yaml:"context"`
+}
+
+// MyksContext is the context of the current operation.
+type MyksContext struct {
+	App       string          `yaml:"app"`
+	Prototype string          `yaml:"prototype"`
+	Step      string          `yaml:"step"`
+	Helm      MyksHelmContext `yaml:"helm"`
+}
+
+// MyksHelmContext is the context of the helm step.
+type MyksHelmContext struct {
+	Chart string `yaml:"chart"`
+}
+
+// AppName returns the name of the application, or the name of its prototype.
+func (a EnvironmentApplication) AppName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Proto
+}
@@ -0,0 +1,24 @@
+package plugin
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// Logger returns a logger writing to stderr in the format of myks, at the log level of myks.
+// Messages are annotated with the environment and application of the invocation.
+func (c *Context) Logger() zerolog.Logger {
+	level, err := zerolog.ParseLevel(c.LogLevel)
+	if err != nil || c.LogLevel == "" {
+		level = zerolog.InfoLevel
+	}
+	logCtx := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).Level(level).With().Timestamp()
+	if c.Env != "" {
+		logCtx = logCtx.Str("env", c.Env)
+	}
+	if c.App != "" {
+		logCtx = logCtx.Str("app", c.App)
+	}
+	return logCtx.Logger()
+}
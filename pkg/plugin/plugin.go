@@ -0,0 +1,235 @@
+// Package plugin helps to write myks plugins in Go.
+//
+// Myks passes the context of a plugin invocation in environment variables and files. The package parses them,
+// decodes the data values, and lists the rendered files:
+//
+//	ctx, err := plugin.Load()
+//	if err != nil {
+//		log.Fatal().Err(err).Msg("Not invoked by myks")
+//	}
+//	log := ctx.Logger()
+//	values, err := ctx.DataValues()
+//	...
+//	files, err := ctx.RenderedFiles()
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables passed to plugins
+const (
+	// ID of the environment, unset for global plugins
+	EnvVarEnv = "MYKS_ENV"
+	// Directory of the environment, unset for global plugins
+	EnvVarEnvDir = "MYKS_ENV_DIR"
+	// Name of the application, only set for application plugins
+	EnvVarApp = "MYKS_APP"
+	// Prototype of the application, only set for application plugins
+	EnvVarAppPrototype = "MYKS_APP_PROTOTYPE"
+	// Directory of the rendered application, only set for application plugins
+	EnvVarRenderedAppDir = "MYKS_RENDERED_APP_DIR"
+	// Directory of the rendered environment, only set for environment plugins
+	EnvVarRenderedEnvDir = "MYKS_RENDERED_ENV_DIR"
+	// Comma-separated names of the selected applications, only set for environment plugins
+	EnvVarApps = "MYKS_APPS"
+	// Root directory of the project, only set for global plugins
+	EnvVarRootDir = "MYKS_ROOT_DIR"
+	// Comma-separated IDs of the selected environments, only set for global plugins
+	EnvVarEnvs = "MYKS_ENVS"
+	// YAML data values of the application or environment, unset if too large
+	EnvVarDataValues = "MYKS_DATA_VALUES"
+	// Directory with the files of the plugin invocation
+	EnvVarContextDir = "MYKS_CONTEXT_DIR"
+	// Log level of myks
+	EnvVarLogLevel = "MYKS_LOG_LEVEL"
+)
+
+// Files in the context directory
+const (
+	// Data values as YAML
+	ContextFileDataValuesYAML = "data-values.yaml"
+	// Data values as JSON
+	ContextFileDataValuesJSON = "data-values.json"
+	// Files of the rendered application, one slash-separated relative path per line
+	ContextFileRenderedFiles = "rendered-files.txt"
+	// Source files of the rendering steps of the application as JSON, keyed by step name
+	ContextFileSourceFiles = "source-files.json"
+)
+
+// Execution scopes of plugins
+const (
+	ScopeApp    = "app"
+	ScopeEnv    = "env"
+	ScopeGlobal = "global"
+)
+
+// ErrNotInvoked is returned by Load if the process was not started by myks.
+var ErrNotInvoked = errors.New("no myks plugin context found in the environment")
+
+// Context is the context of a plugin invocation.
+type Context struct {
+	// Execution scope: app, env, or global
+	Scope string
+
+	Env            string
+	EnvDir         string
+	App            string
+	AppPrototype   string
+	RenderedAppDir string
+	RenderedEnvDir string
+	Apps           []string
+	RootDir        string
+	Envs           []string
+	ContextDir     string
+	LogLevel       string
+
+	dataValues    string
+	hasDataValues bool
+}
+
+// Load reads the context of the plugin invocation from the environment.
+func Load() (*Context, error) {
+	return LoadFrom(os.LookupEnv)
+}
+
+// LoadFrom reads the context of the plugin invocation with a lookup function like os.LookupEnv.
+func LoadFrom(lookup func(string) (string, bool)) (*Context, error) {
+	get := func(name string) string {
+		value, _ := lookup(name)
+		return value
+	}
+	c := &Context{
+		Env:            get(EnvVarEnv),
+		EnvDir:         get(EnvVarEnvDir),
+		App:            get(EnvVarApp),
+		AppPrototype:   get(EnvVarAppPrototype),
+		RenderedAppDir: get(EnvVarRenderedAppDir),
+		RenderedEnvDir: get(EnvVarRenderedEnvDir),
+		Apps:           splitList(get(EnvVarApps)),
+		RootDir:        get(EnvVarRootDir),
+		Envs:           splitList(get(EnvVarEnvs)),
+		ContextDir:     get(EnvVarContextDir),
+		LogLevel:       get(EnvVarLogLevel),
+	}
+	c.dataValues, c.hasDataValues = lookup(EnvVarDataValues)
+
+	switch {
+	case c.App != "":
+		c.Scope = ScopeApp
+	case c.Env != "":
+		c.Scope = ScopeEnv
+	default:
+		if _, ok := lookup(EnvVarEnvs); !ok {
+			return nil, ErrNotInvoked
+		}
+		c.Scope = ScopeGlobal
+	}
+	return c, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// RawDataValues returns the YAML data values of the application or environment.
+// Large data values are read from the context directory.
+func (c *Context) RawDataValues() ([]byte, error) {
+	if c.hasDataValues {
+		return []byte(c.dataValues), nil
+	}
+	if c.ContextDir == "" {
+		return nil, fmt.Errorf("no data values passed to %s plugins", c.Scope)
+	}
+	data, err := os.ReadFile(filepath.Join(c.ContextDir, ContextFileDataValuesYAML))
+	if err != nil {
+		return nil, fmt.Errorf("reading data values: %w", err)
+	}
+	return data, nil
+}
+
+// DataValues decodes the data values into the types of the myks data schema.
+func (c *Context) DataValues() (*DataValues, error) {
+	var values DataValues
+	if err := c.DecodeDataValues(&values); err != nil {
+		return nil, err
+	}
+	return &values, nil
+}
+
+// DecodeDataValues decodes the data values into a custom type with yaml tags,
+// e.g. to access the application scope.
+func (c *Context) DecodeDataValues(v any) error {
+	data, err := c.RawDataValues()
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding data values: %w", err)
+	}
+	return nil
+}
+
+// RenderedFiles returns the paths of the files of the rendered application, joined with RenderedAppDir.
+func (c *Context) RenderedFiles() ([]string, error) {
+	if c.Scope != ScopeApp {
+		return nil, fmt.Errorf("rendered files are only passed to %s plugins", ScopeApp)
+	}
+	var files []string
+	data, err := os.ReadFile(filepath.Join(c.ContextDir, ContextFileRenderedFiles))
+	if err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				files = append(files, filepath.Join(c.RenderedAppDir, filepath.FromSlash(line)))
+			}
+		}
+		return files, scanner.Err()
+	}
+
+	// Older versions of myks don't write the list
+	err = filepath.WalkDir(c.RenderedAppDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("listing rendered files: %w", err)
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// SourceFiles returns the source files of the rendering steps of the application, keyed by step name.
+func (c *Context) SourceFiles() (map[string][]string, error) {
+	if c.Scope != ScopeApp {
+		return nil, fmt.Errorf("source files are only passed to %s plugins", ScopeApp)
+	}
+	data, err := os.ReadFile(filepath.Join(c.ContextDir, ContextFileSourceFiles))
+	if err != nil {
+		return nil, fmt.Errorf("reading source files: %w", err)
+	}
+	var files map[string][]string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("decoding source files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func lookupIn(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	t.Parallel()
+
+	c, err := LoadFrom(lookupIn(map[string]string{
+		EnvVarEnv:            "dev",
+		EnvVarEnvDir:         "envs/dev",
+		EnvVarApp:            "app",
+		EnvVarAppPrototype:   "prototypes/proto",
+		EnvVarRenderedAppDir: "rendered/envs/dev/app",
+		EnvVarDataValues:     "environment:\n  id: dev\n",
+		EnvVarLogLevel:       "debug",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, ScopeApp, c.Scope)
+	assert.Equal(t, "dev", c.Env)
+	assert.Equal(t, "app", c.App)
+	assert.Equal(t, "prototypes/proto", c.AppPrototype)
+	assert.Equal(t, "rendered/envs/dev/app", c.RenderedAppDir)
+	values, err := c.DataValues()
+	require.NoError(t, err)
+	assert.Equal(t, "dev", values.Environment.ID)
+
+	c, err = LoadFrom(lookupIn(map[string]string{EnvVarEnv: "dev", EnvVarApps: "app1,app2"}))
+	require.NoError(t, err)
+	assert.Equal(t, ScopeEnv, c.Scope)
+	assert.Equal(t, []string{"app1", "app2"}, c.Apps)
+
+	c, err = LoadFrom(lookupIn(map[string]string{EnvVarRootDir: "/project", EnvVarEnvs: ""}))
+	require.NoError(t, err)
+	assert.Equal(t, ScopeGlobal, c.Scope)
+	assert.Empty(t, c.Envs)
+	_, err = c.RawDataValues()
+	require.Error(t, err, "global plugins don't get data values")
+
+	_, err = LoadFrom(lookupIn(map[string]string{}))
+	require.ErrorIs(t, err, ErrNotInvoked)
+}
+
+func TestContext_DataValuesFromContextDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ContextFileDataValuesYAML), []byte("application:\n  replicas: 3\n"), 0o600))
+	c, err := LoadFrom(lookupIn(map[string]string{EnvVarEnv: "dev", EnvVarContextDir: dir}))
+	require.NoError(t, err)
+
+	var values struct {
+		Application struct {
+			Replicas int `yaml:"replicas"`
+		} `yaml:"application"`
+	}
+	require.NoError(t, c.DecodeDataValues(&values))
+	assert.Equal(t, 3, values.Application.Replicas)
+}
+
+func TestContext_RenderedFiles(t *testing.T) {
+	t.Parallel()
+
+	renderedDir := t.TempDir()
+	contextDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(renderedDir, "static"), 0o750))
+	for _, name := range []string{"deployment-app.yaml", "static/file.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(renderedDir, name), []byte("test"), 0o600))
+	}
+	c, err := LoadFrom(lookupIn(map[string]string{
+		EnvVarEnv:            "dev",
+		EnvVarApp:            "app",
+		EnvVarRenderedAppDir: renderedDir,
+		EnvVarContextDir:     contextDir,
+	}))
+	require.NoError(t, err)
+	want := []string{filepath.Join(renderedDir, "deployment-app.yaml"), filepath.Join(renderedDir, "static", "file.txt")}
+
+	files, err := c.RenderedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, want, files, "files are listed without the context file")
+
+	// Only listed files are returned
+	require.NoError(t, os.WriteFile(filepath.Join(contextDir, ContextFileRenderedFiles), []byte("deployment-app.yaml\n"), 0o600))
+	files, err = c.RenderedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, want[:1], files)
+
+	c.Scope = ScopeEnv
+	_, err = c.RenderedFiles()
+	require.Error(t, err)
+}
+
+func TestContext_SourceFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ContextFileSourceFiles), []byte(`{"render-ytt": ["envs/dev/_apps/app/ytt/app.yaml"]}`), 0o600))
+	c, err := LoadFrom(lookupIn(map[string]string{EnvVarEnv: "dev", EnvVarApp: "app", EnvVarContextDir: dir}))
+	require.NoError(t, err)
+
+	files, err := c.SourceFiles()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"render-ytt": {"envs/dev/_apps/app/ytt/app.yaml"}}, files)
+}
+
+// TestDataValues_Schema ensures that the types cover every key of the data schema of myks.
+func TestDataValues_Schema(t *testing.T) {
+	t.Parallel()
+
+	schema, err := os.ReadFile("../../internal/myks/assets/data-schema.ytt.yaml")
+	require.NoError(t, err)
+	decoder := yaml.NewDecoder(bytes.NewReader(schema))
+	decoder.KnownFields(true)
+	var values DataValues
+	require.NoError(t, decoder.Decode(&values))
+	assert.True(t, values.ArgoCD.Enabled)
+	assert.Equal(t, "argocd", values.ArgoCD.Namespace)
+	assert.Equal(t, "*", values.ArgoCD.Project.Destination.Namespace)
+	assert.Contains(t, values.ArgoCD.App.SyncPolicy.SyncOptions, "ServerSideApply")
+	assert.True(t, values.Helm.IncludeCRDs)
+	assert.True(t, values.Kbld.Cache)
+}
+
+func TestEnvironmentApplication_AppName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "proto", EnvironmentApplication{Proto: "proto"}.AppName())
+	assert.Equal(t, "app", EnvironmentApplication{Proto: "proto", Name: "app"}.AppName())
+}